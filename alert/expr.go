@@ -0,0 +1,402 @@
+package alert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// kind is the static type of an expression.
+type kind int
+
+const (
+	kindNumber kind = iota
+	kindString
+	kindBool
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindNumber:
+		return "number"
+	case kindString:
+		return "string"
+	default:
+		return "bool"
+	}
+}
+
+// node is a compiled expression. The slack relaxes comparisons of reading fields by
+// the hysteresis of a firing rule so they need to move past the threshold to stop
+// holding. Comparisons of the hour and constants alone are not relaxed.
+type node interface {
+	kind() kind
+	eval(vars map[string]interface{}, slack float64) interface{}
+}
+
+type numberNode float64
+
+func (n numberNode) kind() kind                                       { return kindNumber }
+func (n numberNode) eval(map[string]interface{}, float64) interface{} { return float64(n) }
+
+type stringNode string
+
+func (n stringNode) kind() kind                                       { return kindString }
+func (n stringNode) eval(map[string]interface{}, float64) interface{} { return string(n) }
+
+type boolNode bool
+
+func (n boolNode) kind() kind                                       { return kindBool }
+func (n boolNode) eval(map[string]interface{}, float64) interface{} { return bool(n) }
+
+type identNode struct {
+	name string
+	k    kind
+}
+
+func (n identNode) kind() kind { return n.k }
+func (n identNode) eval(vars map[string]interface{}, _ float64) interface{} {
+	return vars[n.name]
+}
+
+type negNode struct{ x node }
+
+func (n negNode) kind() kind { return kindNumber }
+func (n negNode) eval(vars map[string]interface{}, slack float64) interface{} {
+	return -n.x.eval(vars, slack).(float64)
+}
+
+type notNode struct{ x node }
+
+func (n notNode) kind() kind { return kindBool }
+func (n notNode) eval(vars map[string]interface{}, slack float64) interface{} {
+	// a negated comparison holds when the comparison does not, so it is relaxed the other way
+	return !n.x.eval(vars, -slack).(bool)
+}
+
+type binaryNode struct {
+	op      string
+	x, y    node
+	relaxed bool // a comparison the slack applies to
+}
+
+func (n binaryNode) kind() kind {
+	switch n.op {
+	case "+", "-", "*", "/":
+		return kindNumber
+	default:
+		return kindBool
+	}
+}
+
+func (n binaryNode) eval(vars map[string]interface{}, slack float64) interface{} {
+	switch n.op {
+	case "and":
+		return n.x.eval(vars, slack).(bool) && n.y.eval(vars, slack).(bool)
+	case "or":
+		return n.x.eval(vars, slack).(bool) || n.y.eval(vars, slack).(bool)
+	}
+
+	if !n.relaxed {
+		slack = 0
+	}
+	x, y := n.x.eval(vars, slack), n.y.eval(vars, slack)
+	if n.x.kind() != kindNumber {
+		switch n.op {
+		case "==":
+			return x == y
+		default:
+			return x != y
+		}
+	}
+
+	a, b := x.(float64), y.(float64)
+	switch n.op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	case "/":
+		if b == 0 {
+			return 0.0
+		}
+		return a / b
+	case "<":
+		return a < b+slack
+	case "<=":
+		return a <= b+slack
+	case ">":
+		return a > b-slack
+	case ">=":
+		return a >= b-slack
+	case "==":
+		return a == b
+	default:
+		return a != b
+	}
+}
+
+type token struct {
+	text   string
+	number bool
+	str    bool
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{text: s[i:j], number: true})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, token{text: s[i:j]})
+			i = j
+		case c == '"':
+			j := strings.IndexByte(s[i+1:], '"')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, token{text: s[i+1 : i+1+j], str: true})
+			i += j + 2
+		default:
+			op := string(c)
+			if i+1 < len(s) && s[i+1] == '=' && strings.ContainsRune("<>=!", c) {
+				op = s[i : i+2]
+			}
+			switch op {
+			case "+", "-", "*", "/", "%", "(", ")", "<", ">", "<=", ">=", "==", "!=":
+			default:
+				return nil, fmt.Errorf("unexpected %q at %d", op, i)
+			}
+			tokens = append(tokens, token{text: op})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+// parser compiles expressions like
+//
+//	battery_capacity_soc < 30 and not street_light_status
+//	solar_panel_voltage > 95% * maximum_voltage_supported
+//	charging_state == "floating charging mode"
+//
+// with the usual precedence: or, and, not, comparisons, sums, products.
+type parser struct {
+	tokens  []token
+	pos     int
+	vars    map[string]kind
+	relaxed map[string]bool
+}
+
+// compile parses a condition over vars. Comparisons using any of the relaxed
+// variables are relaxed by the slack when evaluated.
+func compile(s string, vars map[string]kind, relaxed map[string]bool) (node, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, vars: vars, relaxed: relaxed}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if n.kind() != kindBool {
+		return nil, fmt.Errorf("expression is a %s, not a condition", n.kind())
+	}
+	return n, nil
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].str {
+		return p.tokens[p.pos].text
+	}
+	return ""
+}
+
+func (p *parser) logical(op string, operand func() (node, error)) (node, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == op {
+		p.pos++
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if x.kind() != kindBool || y.kind() != kindBool {
+			return nil, fmt.Errorf("%s needs conditions", op)
+		}
+		x = binaryNode{op: op, x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) or() (node, error)  { return p.logical("or", p.and) }
+func (p *parser) and() (node, error) { return p.logical("and", p.not) }
+
+func (p *parser) not() (node, error) {
+	if p.peek() != "not" {
+		return p.comparison()
+	}
+	p.pos++
+	x, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	if x.kind() != kindBool {
+		return nil, fmt.Errorf("not needs a condition")
+	}
+	return notNode{x}, nil
+}
+
+func (p *parser) comparison() (node, error) {
+	x, err := p.arithmetic(p.product, "+", "-")
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.peek()
+	switch op {
+	case "<", "<=", ">", ">=", "==", "!=":
+	default:
+		return x, nil
+	}
+	p.pos++
+	y, err := p.arithmetic(p.product, "+", "-")
+	if err != nil {
+		return nil, err
+	}
+
+	if x.kind() != y.kind() {
+		return nil, fmt.Errorf("cannot compare %s with %s", x.kind(), y.kind())
+	}
+	if x.kind() != kindNumber && op != "==" && op != "!=" {
+		return nil, fmt.Errorf("cannot order %ss", x.kind())
+	}
+	relaxed := false
+	for _, name := range identifiers(binaryNode{x: x, y: y}) {
+		relaxed = relaxed || p.relaxed[name]
+	}
+	return binaryNode{op: op, x: x, y: y, relaxed: relaxed}, nil
+}
+
+func (p *parser) product() (node, error) {
+	return p.arithmetic(p.unary, "*", "/")
+}
+
+func (p *parser) arithmetic(operand func() (node, error), ops ...string) (node, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op == "" || (op != ops[0] && op != ops[1]) {
+			return x, nil
+		}
+		p.pos++
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if x.kind() != kindNumber || y.kind() != kindNumber {
+			return nil, fmt.Errorf("%s needs numbers", op)
+		}
+		x = binaryNode{op: op, x: x, y: y}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	if p.peek() == "-" {
+		p.pos++
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if x.kind() != kindNumber {
+			return nil, fmt.Errorf("- needs a number")
+		}
+		return negNode{x}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+
+	switch {
+	case t.str:
+		return stringNode(t.text), nil
+	case t.number:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		if p.peek() == "%" {
+			p.pos++
+			f /= 100
+		}
+		return numberNode(f), nil
+	case t.text == "(":
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return x, nil
+	case t.text == "true" || t.text == "false":
+		return boolNode(t.text == "true"), nil
+	case unicode.IsLetter(rune(t.text[0])) || t.text[0] == '_':
+		k, ok := p.vars[t.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown variable %q", t.text)
+		}
+		return identNode{name: t.text, k: k}, nil
+	default:
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+}
+
+// identifiers returns the variables an expression uses.
+func identifiers(n node) []string {
+	switch n := n.(type) {
+	case identNode:
+		return []string{n.name}
+	case negNode:
+		return identifiers(n.x)
+	case notNode:
+		return identifiers(n.x)
+	case binaryNode:
+		return append(identifiers(n.x), identifiers(n.y)...)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,69 @@
+package alert
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	vars := map[string]kind{
+		"soc":     kindNumber,
+		"voltage": kindNumber,
+		"max":     kindNumber,
+		"state":   kindString,
+		"load":    kindBool,
+	}
+	values := map[string]interface{}{
+		"soc":     25.0,
+		"voltage": 96.0,
+		"max":     100.0,
+		"state":   "floating charging mode",
+		"load":    true,
+	}
+
+	tests := []struct {
+		expr     string
+		slack    float64
+		expected bool
+		err      string
+	}{
+		{expr: "soc < 30", expected: true},
+		{expr: "soc < 20", expected: false},
+		{expr: "soc < 20", slack: 5, expected: false},
+		{expr: "soc < 22", slack: 5, expected: true},
+		{expr: "not soc > 22", slack: 5, expected: true},
+		{expr: "not soc > 18", slack: 5, expected: false},
+		{expr: "max < 98", slack: 5, expected: false},
+		{expr: "soc < 22 and max < 98", slack: 5, expected: false},
+		{expr: "voltage > 95% * max", expected: true},
+		{expr: "voltage > 0.95 * max + 2", expected: false},
+		{expr: "-soc < -20 and (load or soc > 90)", expected: true},
+		{expr: "state == \"floating charging mode\" and not load", expected: false},
+		{expr: "state != \"boost charging mode\"", expected: true},
+		{expr: "soc / 0 == 0", expected: true},
+		{expr: "soc + 1", err: "expression is a number, not a condition"},
+		{expr: "temperature > 60", err: "unknown variable \"temperature\""},
+		{expr: "state < \"a\"", err: "cannot order strings"},
+		{expr: "state == 1", err: "cannot compare string with number"},
+		{expr: "soc > 1 and 2", err: "and needs conditions"},
+		{expr: "(soc > 1", err: "missing )"},
+		{expr: "soc > 1)", err: "unexpected \")\""},
+		{expr: "soc >", err: "unexpected end of expression"},
+		{expr: "soc = 1", err: "unexpected \"=\" at 4"},
+		{expr: "state == \"open", err: "unterminated string at 9"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			n, err := compile(tc.expr, vars, map[string]bool{"soc": true, "voltage": true})
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, n.eval(values, tc.slack))
+		})
+	}
+}
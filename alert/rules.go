@@ -0,0 +1,339 @@
+// Package alert evaluates threshold rules against readings in-process and produces
+// firing and resolved alert events with a severity.
+//
+// Rules are conditions over the json names of the reading fields, the hour of the
+// day of the reading (hour, e.g. 10.5 for 10:30) and constants such as the product
+// information:
+//
+//	rules:
+//	  - name: low-soc
+//	    expr: battery_capacity_soc < 30
+//	    for: 10m
+//	    hysteresis: 5
+//	    severity: warning
+//	  - name: no-midday-charging
+//	    expr: hour >= 10 and hour < 14 and charging_power == 0
+//	    for: 30m
+//	  - name: pv-over-voltage
+//	    expr: solar_panel_voltage > 95% * maximum_voltage_supported
+//	    severity: critical
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Critical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Critical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "info":
+		*s = Info
+	case "warning":
+		*s = Warning
+	case "critical":
+		*s = Critical
+	default:
+		return fmt.Errorf("invalid severity: %s", text)
+	}
+	return nil
+}
+
+type State int
+
+const (
+	Firing State = iota
+	Resolved
+)
+
+func (s State) String() string {
+	switch s {
+	case Firing:
+		return "firing"
+	case Resolved:
+		return "resolved"
+	default:
+		return "unknown"
+	}
+}
+
+func (s State) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+type Rule struct {
+	Name string `yaml:"name"`
+	Expr string `yaml:"expr"`
+	// For is how long the condition must hold before the rule fires.
+	For time.Duration `yaml:"for"`
+	// Hysteresis is how far past its thresholds a comparison of reading fields has
+	// to move before a firing rule resolves. Comparisons of the hour and constants
+	// alone resolve as soon as they stop holding.
+	Hysteresis  float64  `yaml:"hysteresis"`
+	Severity    Severity `yaml:"severity"`
+	Description string   `yaml:"description"`
+}
+
+// ruleDocument is a Rule as written, telling rules without a severity from info ones.
+type ruleDocument struct {
+	Name        string        `yaml:"name"`
+	Expr        string        `yaml:"expr"`
+	For         time.Duration `yaml:"for"`
+	Hysteresis  float64       `yaml:"hysteresis"`
+	Severity    *Severity     `yaml:"severity"`
+	Description string        `yaml:"description"`
+}
+
+// ParseRules reads rules from a YAML document with a list of rules under "rules".
+// Rules without a severity are warnings.
+func ParseRules(r io.Reader) ([]Rule, error) {
+	var doc struct {
+		Rules []ruleDocument `yaml:"rules"`
+	}
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode rules: %w", err)
+	}
+
+	rules := make([]Rule, 0, len(doc.Rules))
+	for _, r := range doc.Rules {
+		severity := Warning
+		if r.Severity != nil {
+			severity = *r.Severity
+		}
+		rules = append(rules, Rule{
+			Name:        r.Name,
+			Expr:        r.Expr,
+			For:         r.For,
+			Hysteresis:  r.Hysteresis,
+			Severity:    severity,
+			Description: r.Description,
+		})
+	}
+
+	return rules, nil
+}
+
+// Event is a rule starting or stopping to fire. Since is when its condition started
+// to hold, At when the rule fired or resolved.
+type Event struct {
+	Rule        string    `json:"rule"`
+	Severity    Severity  `json:"severity"`
+	State       State     `json:"state"`
+	Description string    `json:"description,omitempty"`
+	Since       time.Time `json:"since"`
+	At          time.Time `json:"at"`
+}
+
+type ruleState struct {
+	Rule
+	condition node
+	fields    []string // reading fields the condition needs
+
+	holding bool
+	since   time.Time
+	firing  bool
+	firedAt time.Time
+}
+
+// Engine evaluates rules against successive readings. It is not safe for
+// concurrent use.
+type Engine struct {
+	rules     []*ruleState
+	constants map[string]float64
+}
+
+// NewEngine compiles rules. Constants are extra numeric variables, see
+// ProductConstants.
+func NewEngine(rules []Rule, constants map[string]float64) (*Engine, error) {
+	vars := map[string]kind{"hour": kindNumber}
+	for name := range constants {
+		vars[name] = kindNumber
+	}
+	// hysteresis applies to comparisons of readings, not to time windows
+	relaxed := map[string]bool{}
+	for name, k := range readingKinds {
+		vars[name] = k
+		relaxed[name] = true
+	}
+
+	e := &Engine{constants: constants}
+	names := map[string]bool{}
+	for _, r := range rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("rule without a name: %s", r.Expr)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("duplicate rule: %s", r.Name)
+		}
+		names[r.Name] = true
+
+		condition, err := compile(r.Expr, vars, relaxed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %s: %w", r.Name, err)
+		}
+
+		var fields []string
+		for _, name := range identifiers(condition) {
+			if _, ok := readingKinds[name]; ok {
+				fields = append(fields, name)
+			}
+		}
+		e.rules = append(e.rules, &ruleState{Rule: r, condition: condition, fields: fields})
+	}
+
+	return e, nil
+}
+
+// ProductConstants are the ratings of a controller as constants for NewEngine.
+func ProductConstants(pi *gorenogymodbus.ProductInformation) map[string]float64 {
+	return map[string]float64{
+		"maximum_voltage_supported": float64(pi.MaximumVoltageSupported),
+		"rated_charging_current":    float64(pi.RatedChargingCurrent),
+		"rated_discharging_current": float64(pi.RatedDischargingCurrent),
+	}
+}
+
+// Evaluate feeds a reading taken at t and returns the events of rules that fired or
+// resolved, in the order of the rules. Rules using fields missing from a partial
+// reading keep their state.
+func (e *Engine) Evaluate(t time.Time, dci *gorenogymodbus.DynamicControllerInformation) []Event {
+	vars := readingValues(dci)
+	vars["hour"] = float64(t.Hour()) + float64(t.Minute())/60 + float64(t.Second())/3600
+	for name, v := range e.constants {
+		vars[name] = v
+	}
+
+	var events []Event
+	for _, r := range e.rules {
+		if !hasAll(dci, r.fields) {
+			continue
+		}
+
+		slack := 0.0
+		if r.firing {
+			slack = r.Hysteresis
+		}
+		holds := r.condition.eval(vars, slack).(bool)
+
+		switch {
+		case holds && !r.holding:
+			r.holding = true
+			r.since = t
+		case !holds && r.holding:
+			r.holding = false
+			if r.firing {
+				r.firing = false
+				events = append(events, r.event(Resolved, t))
+			}
+			continue
+		case !holds:
+			continue
+		}
+
+		if !r.firing && t.Sub(r.since) >= r.For {
+			r.firing = true
+			r.firedAt = t
+			events = append(events, r.event(Firing, t))
+		}
+	}
+
+	return events
+}
+
+// Firing returns the firing events of the rules firing now.
+func (e *Engine) Firing() []Event {
+	var events []Event
+	for _, r := range e.rules {
+		if r.firing {
+			events = append(events, r.event(Firing, r.firedAt))
+		}
+	}
+	return events
+}
+
+func (r *ruleState) event(state State, at time.Time) Event {
+	return Event{
+		Rule:        r.Name,
+		Severity:    r.Severity,
+		State:       state,
+		Description: r.Description,
+		Since:       r.since,
+		At:          at,
+	}
+}
+
+func hasAll(dci *gorenogymodbus.DynamicControllerInformation, fields []string) bool {
+	for _, f := range fields {
+		if !dci.Has(f) {
+			return false
+		}
+	}
+	return true
+}
+
+// readingKinds are the types of the reading fields usable in conditions.
+var readingKinds = func() map[string]kind {
+	kinds := map[string]kind{}
+	for _, f := range (&gorenogymodbus.DynamicControllerInformation{}).Fields() {
+		switch f.Value.(type) {
+		case decimal.Decimal, int:
+			kinds[f.Name] = kindNumber
+		case bool:
+			kinds[f.Name] = kindBool
+		case string:
+			kinds[f.Name] = kindString
+		}
+	}
+	return kinds
+}()
+
+func readingValues(dci *gorenogymodbus.DynamicControllerInformation) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, f := range dci.Fields() {
+		if _, ok := readingKinds[f.Name]; !ok {
+			continue
+		}
+
+		switch v := f.Value.(type) {
+		case decimal.Decimal:
+			values[f.Name] = v.InexactFloat64()
+		case int:
+			values[f.Name] = float64(v)
+		default:
+			values[f.Name] = v
+		}
+	}
+	return values
+}
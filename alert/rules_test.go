@@ -0,0 +1,163 @@
+package alert_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/alert"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rules = `rules:
+  - name: low-soc
+    expr: battery_capacity_soc < 30
+    for: 10m
+    hysteresis: 5
+  - name: hot-controller
+    expr: controller_temperature > 60
+    severity: critical
+    description: controller is overheating
+  - name: no-midday-charging
+    expr: hour >= 10 and hour < 14 and charging_power == 0
+    for: 30m
+    severity: info
+  - name: pv-over-voltage
+    expr: solar_panel_voltage > 95% * maximum_voltage_supported
+    severity: critical
+`
+
+func TestParseRules(t *testing.T) {
+	parsed, err := alert.ParseRules(strings.NewReader(rules))
+	require.NoError(t, err)
+	assert.Equal(t, []alert.Rule{
+		{Name: "low-soc", Expr: "battery_capacity_soc < 30", For: 10 * time.Minute, Hysteresis: 5, Severity: alert.Warning},
+		{Name: "hot-controller", Expr: "controller_temperature > 60", Severity: alert.Critical, Description: "controller is overheating"},
+		{Name: "no-midday-charging", Expr: "hour >= 10 and hour < 14 and charging_power == 0", For: 30 * time.Minute, Severity: alert.Info},
+		{Name: "pv-over-voltage", Expr: "solar_panel_voltage > 95% * maximum_voltage_supported", Severity: alert.Critical},
+	}, parsed)
+
+	_, err = alert.ParseRules(strings.NewReader("rules:\n  - name: x\n    expression: soc < 1\n"))
+	assert.ErrorContains(t, err, "field expression not found")
+
+	_, err = alert.ParseRules(strings.NewReader("rules:\n  - name: x\n    severity: page\n"))
+	assert.ErrorContains(t, err, "invalid severity: page")
+}
+
+func TestNewEngine(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []alert.Rule
+		expected string
+	}{
+		{
+			name:     "unnamed",
+			rules:    []alert.Rule{{Expr: "battery_capacity_soc < 30"}},
+			expected: "rule without a name: battery_capacity_soc < 30",
+		},
+		{
+			name:     "duplicate",
+			rules:    []alert.Rule{{Name: "a", Expr: "hour > 1"}, {Name: "a", Expr: "hour > 2"}},
+			expected: "duplicate rule: a",
+		},
+		{
+			name:     "unknown constant",
+			rules:    []alert.Rule{{Name: "a", Expr: "solar_panel_voltage > maximum_voltage_supported"}},
+			expected: "invalid rule a: unknown variable \"maximum_voltage_supported\"",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := alert.NewEngine(tc.rules, nil)
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
+
+func TestEngine(t *testing.T) {
+	parsed, err := alert.ParseRules(strings.NewReader(rules))
+	require.NoError(t, err)
+	e, err := alert.NewEngine(parsed, alert.ProductConstants(&gorenogymodbus.ProductInformation{MaximumVoltageSupported: 100}))
+	require.NoError(t, err)
+
+	start := time.Date(2023, 8, 10, 6, 0, 0, 0, time.UTC)
+	reading := func(soc int, temperature int, chargingPower float64, pvVoltage float64) *gorenogymodbus.DynamicControllerInformation {
+		return &gorenogymodbus.DynamicControllerInformation{
+			BatteryCapacitySOC:    soc,
+			ControllerTemperature: temperature,
+			ChargingPower:         decimal.NewFromFloat(chargingPower),
+			SolarPanelVoltage:     decimal.NewFromFloat(pvVoltage),
+		}
+	}
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	event := func(rule string, severity alert.Severity, state alert.State, since, now int) alert.Event {
+		return alert.Event{Rule: rule, Severity: severity, State: state, Since: at(since), At: at(now)}
+	}
+
+	steps := []struct {
+		minutes  int
+		reading  *gorenogymodbus.DynamicControllerInformation
+		expected []alert.Event
+	}{
+		{minutes: 0, reading: reading(50, 30, 0, 0)},
+		{minutes: 5, reading: reading(29, 30, 0, 0)},
+		{minutes: 10, reading: reading(28, 30, 0, 0)},
+		{minutes: 15, reading: reading(27, 30, 0, 0), expected: []alert.Event{event("low-soc", alert.Warning, alert.Firing, 5, 15)}},
+		// within the hysteresis
+		{minutes: 20, reading: reading(33, 65, 50, 0), expected: []alert.Event{
+			{Rule: "hot-controller", Severity: alert.Critical, State: alert.Firing, Description: "controller is overheating", Since: at(20), At: at(20)},
+		}},
+		{minutes: 25, reading: reading(35, 50, 50, 96), expected: []alert.Event{
+			event("low-soc", alert.Warning, alert.Resolved, 5, 25),
+			{Rule: "hot-controller", Severity: alert.Critical, State: alert.Resolved, Description: "controller is overheating", Since: at(20), At: at(25)},
+			event("pv-over-voltage", alert.Critical, alert.Firing, 25, 25),
+		}},
+		{minutes: 240, reading: reading(80, 30, 0, 20), expected: []alert.Event{event("pv-over-voltage", alert.Critical, alert.Resolved, 25, 240)}},
+		{minutes: 270, reading: reading(80, 30, 0, 20), expected: []alert.Event{event("no-midday-charging", alert.Info, alert.Firing, 240, 270)}},
+	}
+
+	for _, s := range steps {
+		assert.Equal(t, s.expected, e.Evaluate(at(s.minutes), s.reading), "at %d minutes", s.minutes)
+	}
+	assert.Equal(t, []alert.Event{event("no-midday-charging", alert.Info, alert.Firing, 240, 270)}, e.Firing())
+}
+
+func TestEngineTimeWindowHysteresis(t *testing.T) {
+	e, err := alert.NewEngine([]alert.Rule{{
+		Name:       "midday-low-soc",
+		Expr:       "hour >= 10 and hour < 14 and battery_capacity_soc < 30",
+		Hysteresis: 5,
+	}}, nil)
+	require.NoError(t, err)
+
+	start := time.Date(2023, 8, 10, 13, 50, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	reading := func(soc int) *gorenogymodbus.DynamicControllerInformation {
+		return &gorenogymodbus.DynamicControllerInformation{BatteryCapacitySOC: soc}
+	}
+	event := func(state alert.State, now int) []alert.Event {
+		return []alert.Event{{Rule: "midday-low-soc", Severity: alert.Info, State: state, Since: at(0), At: at(now)}}
+	}
+
+	assert.Equal(t, event(alert.Firing, 0), e.Evaluate(at(0), reading(25)))
+	// the soc is within the hysteresis
+	assert.Empty(t, e.Evaluate(at(5), reading(32)))
+	// the hour is not, the window closes at 14:00
+	assert.Equal(t, event(alert.Resolved, 10), e.Evaluate(at(10), reading(25)))
+	assert.Empty(t, e.Evaluate(at(60), reading(25)))
+}
+
+func TestEnginePartialReading(t *testing.T) {
+	e, err := alert.NewEngine([]alert.Rule{{Name: "low-soc", Expr: "battery_capacity_soc < 30"}}, nil)
+	require.NoError(t, err)
+
+	// a reading of the controller temperature only says nothing about the soc
+	data := make([]byte, 2)
+	dci, err := gorenogymodbus.ParseRange(0x103, data)
+	require.NoError(t, err)
+	assert.Empty(t, e.Evaluate(time.Now(), dci))
+}
@@ -0,0 +1,205 @@
+// Package backup snapshots the writable settings of a controller to a versioned
+// YAML or JSON file, diffs files against devices and restores them.
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the version of the backup file format written by Encode.
+const Version = 1
+
+type Format int
+
+const (
+	YAML Format = iota
+	JSON
+)
+
+// FormatFromPath picks JSON for .json files and YAML for anything else.
+func FormatFromPath(path string) Format {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return JSON
+	}
+	return YAML
+}
+
+// Device identifies the controller a backup was taken from.
+type Device struct {
+	Model           string `json:"model" yaml:"model"`
+	SerialNumber    string `json:"serial_number" yaml:"serial_number"`
+	SoftwareVersion string `json:"software_version" yaml:"software_version"`
+	HardwareVersion string `json:"hardware_version" yaml:"hardware_version"`
+}
+
+type File struct {
+	Version  int                     `json:"version" yaml:"version"`
+	Created  time.Time               `json:"created" yaml:"created"`
+	Device   Device                  `json:"device" yaml:"device"`
+	Settings gorenogymodbus.Settings `json:"settings" yaml:"settings"`
+}
+
+// Snapshot reads the product information and settings of a controller.
+func Snapshot(mc *gorenogymodbus.ModbusClient, now time.Time) (*File, error) {
+	pi, err := mc.ReadProductInformation()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read product information: %w", err)
+	}
+
+	settings, err := mc.ReadSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+
+	return &File{
+		Version: Version,
+		Created: now,
+		Device: Device{
+			Model:           pi.Model,
+			SerialNumber:    pi.SerialNumber,
+			SoftwareVersion: pi.SoftwareVersion,
+			HardwareVersion: pi.HardwareVersion,
+		},
+		Settings: *settings,
+	}, nil
+}
+
+func Encode(w io.Writer, format Format, file *File) error {
+	switch format {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file); err != nil {
+			return fmt.Errorf("failed to encode backup: %w", err)
+		}
+	default:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(file); err != nil {
+			return fmt.Errorf("failed to encode backup: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return fmt.Errorf("failed to encode backup: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Decode reads a backup, rejecting unknown fields and versions newer than Version.
+func Decode(r io.Reader, format Format) (*File, error) {
+	var file File
+	switch format {
+	case JSON:
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&file); err != nil {
+			return nil, fmt.Errorf("failed to decode backup: %w", err)
+		}
+	default:
+		dec := yaml.NewDecoder(r)
+		dec.KnownFields(true)
+		if err := dec.Decode(&file); err != nil {
+			return nil, fmt.Errorf("failed to decode backup: %w", err)
+		}
+	}
+
+	if file.Version == 0 {
+		return nil, fmt.Errorf("backup has no version")
+	}
+	if file.Version > Version {
+		return nil, fmt.Errorf("unsupported backup version: %d", file.Version)
+	}
+
+	return &file, nil
+}
+
+// Change is a setting that differs between a backup and a device.
+type Change struct {
+	Field  string `json:"field"`
+	Backup string `json:"backup"`
+	Device string `json:"device"`
+}
+
+// Diff returns the settings that differ between a backup and a device. The read only
+// recognized voltage is left out.
+func Diff(backup, device *gorenogymodbus.Settings) []Change {
+	b := reflect.ValueOf(*backup)
+	d := reflect.ValueOf(*device)
+	t := b.Type()
+
+	var changes []Change
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "recognized_voltage" {
+			continue
+		}
+
+		bv, dv := b.Field(i).Interface(), d.Field(i).Interface()
+		if bd, ok := bv.(decimal.Decimal); ok {
+			if bd.Equal(dv.(decimal.Decimal)) {
+				continue
+			}
+		} else if bv == dv {
+			continue
+		}
+
+		changes = append(changes, Change{Field: name, Backup: fmt.Sprint(bv), Device: fmt.Sprint(dv)})
+	}
+
+	return changes
+}
+
+// Plan is what restoring a backup to a device would do.
+type Plan struct {
+	Changes []Change                       `json:"changes"`
+	Writes  []gorenogymodbus.SettingsWrite `json:"writes"`
+}
+
+// NewPlan validates the settings of a backup and plans the register writes that
+// bring a device's settings to them.
+func NewPlan(file *File, device *gorenogymodbus.Settings) (*Plan, error) {
+	if err := file.Settings.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid backup settings: %w", err)
+	}
+
+	writes, err := gorenogymodbus.PlanSettingsWrites(device, &file.Settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan settings writes: %w", err)
+	}
+
+	return &Plan{Changes: Diff(&file.Settings, device), Writes: writes}, nil
+}
+
+// Restore writes the settings of a backup to a controller and returns the plan it
+// followed. With dryRun set nothing is written.
+func Restore(mc *gorenogymodbus.ModbusClient, file *File, dryRun bool) (*Plan, error) {
+	device, err := mc.ReadSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+
+	plan, err := NewPlan(file, device)
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		return plan, nil
+	}
+	if err := mc.WriteSettings(plan.Writes); err != nil {
+		return plan, err
+	}
+
+	return plan, nil
+}
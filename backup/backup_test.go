@@ -0,0 +1,120 @@
+package backup_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/backup"
+	"github.com/michaelpeterswa/go-renogy-modbus/simulator"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+
+func simulated() (*gorenogymodbus.ModbusClient, *simulator.Model) {
+	m := simulator.NewModel(simulator.ModelConfig{Clock: func() time.Time { return now }})
+	return &gorenogymodbus.ModbusClient{Client: simulator.NewServer(1, m).Client()}, m
+}
+
+func TestEncodeDecode(t *testing.T) {
+	mc, _ := simulated()
+	file, err := backup.Snapshot(mc, now)
+	require.NoError(t, err)
+	assert.Equal(t, backup.Version, file.Version)
+	assert.Equal(t, "RNG-CTRL-RVR40", file.Device.Model)
+
+	for _, format := range []backup.Format{backup.YAML, backup.JSON} {
+		var b bytes.Buffer
+		require.NoError(t, backup.Encode(&b, format, file))
+
+		result, err := backup.Decode(&b, format)
+		require.NoError(t, err)
+		assert.Empty(t, backup.Diff(&file.Settings, &result.Settings))
+		assert.Equal(t, file.Device, result.Device)
+		assert.True(t, file.Created.Equal(result.Created))
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		format   backup.Format
+		expected string
+	}{
+		{
+			name:     "missing version",
+			input:    "settings:\n  battery_type: gel\n",
+			expected: "backup has no version",
+		},
+		{
+			name:     "newer version",
+			input:    `{"version": 2}`,
+			format:   backup.JSON,
+			expected: "unsupported backup version: 2",
+		},
+		{
+			name:     "unknown field",
+			input:    "version: 1\nsettings:\n  battery_chemistry: gel\n",
+			expected: "failed to decode backup: yaml: unmarshal errors:\n  line 3: field battery_chemistry not found in type gorenogymodbus.Settings",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := backup.Decode(strings.NewReader(tc.input), tc.format)
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	assert.Equal(t, backup.JSON, backup.FormatFromPath("rover.JSON"))
+	assert.Equal(t, backup.YAML, backup.FormatFromPath("rover.yaml"))
+	assert.Equal(t, backup.YAML, backup.FormatFromPath("rover"))
+}
+
+func TestRestore(t *testing.T) {
+	mc, _ := simulated()
+	file, err := backup.Snapshot(mc, now)
+	require.NoError(t, err)
+
+	// a unit set up for gel batteries that should get the backup
+	target, _ := simulated()
+	gel := file.Settings
+	gel.BatteryType = gorenogymodbus.GelBattery.String()
+	gel.BoostChargingVoltage = decimal.NewFromFloat(14.2)
+	writes, err := gorenogymodbus.PlanSettingsWrites(&file.Settings, &gel)
+	require.NoError(t, err)
+	require.NoError(t, target.WriteSettings(writes))
+
+	plan, err := backup.Restore(target, file, true)
+	require.NoError(t, err)
+	assert.Equal(t, []backup.Change{
+		{Field: "battery_type", Backup: "sealed", Device: "gel"},
+		{Field: "boost_charging_voltage", Backup: "14.4", Device: "14.2"},
+	}, plan.Changes)
+	assert.Equal(t, []gorenogymodbus.SettingsWrite{
+		{Address: 0xE004, Values: []uint16{0x0002}, Fields: []string{"battery_type"}},
+		{Address: 0xE008, Values: []uint16{0x0090}, Fields: []string{"boost_charging_voltage"}},
+	}, plan.Writes)
+
+	settings, err := target.ReadSettings()
+	require.NoError(t, err)
+	assert.Equal(t, "gel", settings.BatteryType)
+
+	_, err = backup.Restore(target, file, false)
+	require.NoError(t, err)
+	settings, err = target.ReadSettings()
+	require.NoError(t, err)
+	assert.Empty(t, backup.Diff(&file.Settings, settings))
+
+	file.Settings.FloatingChargingVoltage = decimal.NewFromFloat(15)
+	_, err = backup.Restore(target, file, false)
+	assert.ErrorContains(t, err, "invalid backup settings: boost_charging_voltage must be >= floating_charging_voltage")
+}
@@ -0,0 +1,253 @@
+// Package capture reads and writes capture files: a header describing the device
+// followed by timestamped records of raw holding registers.
+//
+// A capture file starts with the magic "RNGYCAP\x00", a big endian uint16 format
+// version and a uint32 length prefixed json header. Each record that follows is a
+// uint32 length, the record payload and a CRC-32 (IEEE) of the payload. A version 1
+// payload is the timestamp in unix nanoseconds (int64), the slave id (uint8), the
+// start address (uint16) and the raw register bytes.
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"runtime/debug"
+	"time"
+)
+
+const (
+	Version uint16 = 1
+
+	modulePath = "github.com/michaelpeterswa/go-renogy-modbus"
+
+	// maxHeaderLength and maxRecordLength bound allocations when reading corrupt files.
+	maxHeaderLength = 1 << 16
+	maxRecordLength = 1 << 12
+
+	recordFixedLength = 8 + 1 + 2
+)
+
+var magic = [8]byte{'R', 'N', 'G', 'Y', 'C', 'A', 'P', 0}
+
+var ErrChecksum = errors.New("record checksum mismatch")
+
+type Header struct {
+	DeviceModel    string    `json:"device_model,omitempty"`
+	DeviceSerial   string    `json:"device_serial,omitempty"`
+	LibraryVersion string    `json:"library_version,omitempty"` // filled in by NewWriter when empty
+	Created        time.Time `json:"created"`                   // filled in by NewWriter when zero
+	Comment        string    `json:"comment,omitempty"`
+}
+
+type Record struct {
+	Timestamp time.Time
+	SlaveID   byte
+	Address   uint16 // start address of the registers
+	Data      []byte // raw register bytes, two per register
+}
+
+// Quantity returns the number of registers in the record.
+func (r Record) Quantity() uint16 {
+	return uint16(len(r.Data) / 2)
+}
+
+type Writer struct {
+	w *bufio.Writer
+}
+
+// NewWriter writes the file header to w and returns a Writer for its records.
+func NewWriter(w io.Writer, header Header) (*Writer, error) {
+	if header.LibraryVersion == "" {
+		header.LibraryVersion = libraryVersion()
+	}
+	if header.Created.IsZero() {
+		header.Created = time.Now()
+	}
+
+	h, err := json.Marshal(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal header: %w", err)
+	}
+
+	bw := bufio.NewWriter(w)
+	b := append([]byte{}, magic[:]...)
+	b = binary.BigEndian.AppendUint16(b, Version)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(h)))
+	b = append(b, h...)
+
+	_, err = bw.Write(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	return &Writer{w: bw}, bw.Flush()
+}
+
+// Write appends a record and flushes it to the underlying writer, so a capture
+// interrupted part way through stays readable.
+func (cw *Writer) Write(r Record) error {
+	if len(r.Data)%2 != 0 {
+		return fmt.Errorf("data length is not a whole number of registers: %d", len(r.Data))
+	}
+	if recordFixedLength+len(r.Data) > maxRecordLength {
+		return fmt.Errorf("record too long: %d bytes", len(r.Data))
+	}
+
+	payload := binary.BigEndian.AppendUint64(nil, uint64(r.Timestamp.UnixNano()))
+	payload = append(payload, r.SlaveID)
+	payload = binary.BigEndian.AppendUint16(payload, r.Address)
+	payload = append(payload, r.Data...)
+
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	b = append(b, payload...)
+	b = binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(payload))
+
+	_, err := cw.w.Write(b)
+	if err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+
+	return cw.w.Flush()
+}
+
+type Reader struct {
+	r       *bufio.Reader
+	header  Header
+	version uint16
+}
+
+// NewReader reads the file header from r and returns a Reader for its records.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+
+	var m [8]byte
+	_, err := io.ReadFull(br, m[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read magic: %w", err)
+	}
+	if m != magic {
+		return nil, fmt.Errorf("not a capture file")
+	}
+
+	var prefix [6]byte
+	_, err = io.ReadFull(br, prefix[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	version := binary.BigEndian.Uint16(prefix[0:2])
+	if version == 0 || version > Version {
+		return nil, fmt.Errorf("unsupported capture format version: %d", version)
+	}
+
+	length := binary.BigEndian.Uint32(prefix[2:6])
+	if length > maxHeaderLength {
+		return nil, fmt.Errorf("header too long: %d bytes", length)
+	}
+
+	h := make([]byte, length)
+	_, err = io.ReadFull(br, h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	var header Header
+	err = json.Unmarshal(h, &header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal header: %w", err)
+	}
+
+	return &Reader{
+		r:       br,
+		header:  header,
+		version: version,
+	}, nil
+}
+
+func (cr *Reader) Header() Header {
+	return cr.header
+}
+
+// Version returns the format version of the file being read.
+func (cr *Reader) Version() uint16 {
+	return cr.version
+}
+
+// Next returns the next record, or io.EOF at the end of the file. A record that
+// fails its checksum is returned with ErrChecksum, and reading can continue.
+func (cr *Reader) Next() (Record, error) {
+	var l [4]byte
+	_, err := io.ReadFull(cr.r, l[:])
+	if err == io.EOF {
+		return Record{}, io.EOF
+	}
+	if err != nil {
+		return Record{}, fmt.Errorf("failed to read record: %w", err)
+	}
+
+	length := binary.BigEndian.Uint32(l[:])
+	if length < recordFixedLength || length > maxRecordLength {
+		return Record{}, fmt.Errorf("invalid record length: %d", length)
+	}
+
+	b := make([]byte, length+4)
+	_, err = io.ReadFull(cr.r, b)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return Record{}, fmt.Errorf("failed to read record: %w", err)
+	}
+	payload := b[:length]
+
+	r := Record{
+		Timestamp: time.Unix(0, int64(binary.BigEndian.Uint64(payload[0:8]))),
+		SlaveID:   payload[8],
+		Address:   binary.BigEndian.Uint16(payload[9:11]),
+		Data:      payload[11:],
+	}
+
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(b[length:]) {
+		return r, ErrChecksum
+	}
+
+	return r, nil
+}
+
+// ReadAll reads the remaining records.
+func (cr *Reader) ReadAll() ([]Record, error) {
+	var records []Record
+	for {
+		r, err := cr.Next()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, r)
+	}
+}
+
+func libraryVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+
+	return ""
+}
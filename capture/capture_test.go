@@ -0,0 +1,123 @@
+package capture_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/michaelpeterswa/go-renogy-modbus/capture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	var b bytes.Buffer
+	created := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+
+	w, err := capture.NewWriter(&b, capture.Header{
+		DeviceModel:    "RNG-CTRL-RVR40",
+		DeviceSerial:   "1234",
+		LibraryVersion: "v1.0.0",
+		Created:        created,
+	})
+	require.NoError(t, err)
+
+	records := []capture.Record{
+		{Timestamp: created.Add(time.Second), SlaveID: 1, Address: 0x100, Data: []byte{0x00, 0x64, 0x00, 0x88}},
+		{Timestamp: created.Add(2 * time.Second), SlaveID: 2, Address: 0xE002, Data: []byte{0x00, 0x64}},
+	}
+	for _, r := range records {
+		require.NoError(t, w.Write(r))
+	}
+	assert.Error(t, w.Write(capture.Record{Data: []byte{0x01}}))
+
+	r, err := capture.NewReader(&b)
+	require.NoError(t, err)
+	assert.Equal(t, capture.Version, r.Version())
+	assert.Equal(t, "RNG-CTRL-RVR40", r.Header().DeviceModel)
+	assert.Equal(t, "v1.0.0", r.Header().LibraryVersion)
+	assert.True(t, created.Equal(r.Header().Created))
+
+	result, err := r.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	for i := range records {
+		assert.True(t, records[i].Timestamp.Equal(result[i].Timestamp))
+		assert.Equal(t, records[i].SlaveID, result[i].SlaveID)
+		assert.Equal(t, records[i].Address, result[i].Address)
+		assert.Equal(t, records[i].Data, result[i].Data)
+	}
+	assert.Equal(t, uint16(2), result[0].Quantity())
+}
+
+func TestReaderErrors(t *testing.T) {
+	var valid bytes.Buffer
+	w, err := capture.NewWriter(&valid, capture.Header{})
+	require.NoError(t, err)
+	headerLength := valid.Len()
+	require.NoError(t, w.Write(capture.Record{Timestamp: time.Unix(0, 0), SlaveID: 1, Address: 0x100, Data: []byte{0x00, 0x64}}))
+	require.NoError(t, w.Write(capture.Record{Timestamp: time.Unix(1, 0), SlaveID: 1, Address: 0x100, Data: []byte{0x00, 0x65}}))
+
+	t.Run("not a capture file", func(t *testing.T) {
+		_, err := capture.NewReader(bytes.NewReader([]byte("2023-08-10 70 bytes of registers")))
+		assert.Error(t, err)
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		b := append([]byte{}, valid.Bytes()...)
+		b[9] = 0xFF
+		_, err := capture.NewReader(bytes.NewReader(b))
+		assert.Error(t, err)
+	})
+
+	t.Run("corrupt record", func(t *testing.T) {
+		b := append([]byte{}, valid.Bytes()...)
+		b[headerLength+4+12] ^= 0x01
+
+		r, err := capture.NewReader(bytes.NewReader(b))
+		require.NoError(t, err)
+
+		_, err = r.Next()
+		assert.True(t, errors.Is(err, capture.ErrChecksum))
+
+		record, err := r.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0x00, 0x65}, record.Data)
+
+		_, err = r.Next()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("truncated record", func(t *testing.T) {
+		b := valid.Bytes()[:valid.Len()-3]
+
+		r, err := capture.NewReader(bytes.NewReader(b))
+		require.NoError(t, err)
+
+		_, err = r.Next()
+		assert.NoError(t, err)
+		_, err = r.Next()
+		assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+	})
+
+	t.Run("truncated after record length", func(t *testing.T) {
+		r, err := capture.NewReader(bytes.NewReader(valid.Bytes()[:headerLength+4]))
+		require.NoError(t, err)
+
+		_, err = r.Next()
+		assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		errRead := errors.New("device unplugged")
+		r, err := capture.NewReader(io.MultiReader(bytes.NewReader(valid.Bytes()[:headerLength+6]), iotest.ErrReader(errRead)))
+		require.NoError(t, err)
+
+		_, err = r.Next()
+		assert.True(t, errors.Is(err, errRead))
+		assert.False(t, errors.Is(err, io.ErrUnexpectedEOF))
+	})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/backup"
+	"github.com/michaelpeterswa/go-renogy-modbus/reconcile"
+)
+
+func applySettings(mc *gorenogymodbus.ModbusClient, args []string, stdout io.Writer, f format) error {
+	flags := flag.NewFlagSet("apply", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only report what would change")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one desired state document")
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to open desired state document: %w", err)
+	}
+	defer file.Close()
+
+	doc, err := reconcile.Decode(file, backup.FormatFromPath(flags.Arg(0)))
+	if err != nil {
+		return err
+	}
+
+	report, err := reconcile.Reconcile(mc, doc, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	if f == formatJSON {
+		if err := render(stdout, f, report); err != nil {
+			return err
+		}
+	} else {
+		var records []interface{}
+		for i := range report.Fields {
+			records = append(records, &report.Fields[i])
+		}
+		if err := renderList(stdout, f, records...); err != nil {
+			return err
+		}
+	}
+
+	if report.Failed() {
+		return fmt.Errorf("%d of %d settings failed", report.Count(reconcile.StatusFailed), len(report.Fields))
+	}
+	return nil
+}
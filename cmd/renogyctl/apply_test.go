@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	port := simulate(t)
+	path := filepath.Join(t.TempDir(), "site.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("version: 1\nsettings:\n  load_working_mode: 17\n"), 0o600))
+
+	var b bytes.Buffer
+	require.NoError(t, run([]string{"-port", port, "-format", "csv", "apply", "-dry-run", path}, &b))
+	assert.Equal(t, "field,status,before,desired,after,error\nload_working_mode,pending,15,17,15,\n", b.String())
+
+	b.Reset()
+	require.NoError(t, run([]string{"-port", port, "-format", "csv", "apply", path}, &b))
+	assert.Equal(t, "field,status,before,desired,after,error\nload_working_mode,changed,15,17,17,\n", b.String())
+
+	b.Reset()
+	require.NoError(t, run([]string{"-port", port, "-format", "csv", "apply", path}, &b))
+	assert.Equal(t, "field,status,before,desired,after,error\nload_working_mode,compliant,17,17,17,\n", b.String())
+}
+
+func TestListProfiles(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, run([]string{"-format", "csv", "profiles"}, &b))
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	require.Len(t, lines, 16)
+	assert.Equal(t, "agm-12v,12,sealed,14.6,14.4,13.8,13.2,11.1,12V absorbed glass mat (sealed) lead acid", lines[1])
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/backup"
+)
+
+func backupSettings(mc *gorenogymodbus.ModbusClient, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	output := flags.String("o", "", "file to write, .json for JSON and YAML otherwise (default stdout as YAML)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	file, err := backup.Snapshot(mc, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return backup.Encode(stdout, backup.YAML, file)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	if err := backup.Encode(f, backup.FormatFromPath(*output), file); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func loadBackup(args []string) (*backup.File, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected one backup file")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer f.Close()
+
+	return backup.Decode(f, backup.FormatFromPath(args[0]))
+}
+
+func diffSettings(mc *gorenogymodbus.ModbusClient, args []string, stdout io.Writer, f format) error {
+	file, err := loadBackup(args)
+	if err != nil {
+		return err
+	}
+
+	settings, err := mc.ReadSettings()
+	if err != nil {
+		return err
+	}
+
+	changes := backup.Diff(&file.Settings, settings)
+	if len(changes) == 0 && f == formatTable {
+		fmt.Fprintln(stdout, "no differences")
+		return nil
+	}
+
+	var records []interface{}
+	for i := range changes {
+		records = append(records, &changes[i])
+	}
+	return renderList(stdout, f, records...)
+}
+
+func restoreSettings(mc *gorenogymodbus.ModbusClient, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only print the register writes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	file, err := loadBackup(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	plan, err := backup.Restore(mc, file, *dryRun)
+	if plan != nil {
+		if len(plan.Writes) == 0 {
+			fmt.Fprintln(stdout, "settings already match the backup")
+		}
+		for _, w := range plan.Writes {
+			fmt.Fprintln(stdout, "write", w)
+		}
+	}
+	return err
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupRestore(t *testing.T) {
+	port := simulate(t)
+	path := filepath.Join(t.TempDir(), "rover.yaml")
+
+	var b bytes.Buffer
+	require.NoError(t, run([]string{"-port", port, "backup", "-o", path}, &b))
+
+	require.NoError(t, run([]string{"-port", port, "diff", path}, &b))
+	assert.Equal(t, "no differences\n", b.String())
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	contents = []byte(strings.Replace(string(contents), "battery_type: sealed", "battery_type: gel", 1))
+	require.NoError(t, os.WriteFile(path, contents, 0o600))
+
+	b.Reset()
+	require.NoError(t, run([]string{"-port", port, "-format", "csv", "diff", path}, &b))
+	assert.Equal(t, "field,backup,device\nbattery_type,gel,sealed\n", b.String())
+
+	b.Reset()
+	require.NoError(t, run([]string{"-port", port, "restore", "-dry-run", path}, &b))
+	assert.Equal(t, "write 0xE004 = 0x0003 (battery_type)\n", b.String())
+
+	b.Reset()
+	require.NoError(t, run([]string{"-port", port, "restore", path}, &b))
+	b.Reset()
+	require.NoError(t, run([]string{"-port", port, "diff", path}, &b))
+	assert.Equal(t, "no differences\n", b.String())
+}
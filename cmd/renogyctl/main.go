@@ -0,0 +1,156 @@
+// Command renogyctl reads a Renogy charge controller over Modbus.
+//
+//	renogyctl [flags] <command> [command flags]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+)
+
+const usage = `usage: renogyctl [flags] <command>
+
+commands:
+  read       dynamic controller information (0x100-0x122)
+  product    product information (0x0A-0x1A)
+  settings   battery and load settings (0xE002-0xE01D)
+  history    daily history, -days n for the last n days (0xF000 onwards)
+  watch      live dashboard polling every -interval, plain lines when not a terminal
+  backup     snapshot the writable settings to -o file, YAML or .json
+  diff       compare a backup file with the device settings
+  restore    write a backup file to the device, -dry-run to only print the writes
+  apply      reconcile the device with a desired state document, -dry-run to only report
+  profiles   list the preset charge profiles a document can name
+
+flags:
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "renogyctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("renogyctl", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	port := flags.String("port", "/dev/ttyUSB0", "serial device, or host:port for the tcp transport")
+	transport := flags.String("transport", string(gorenogymodbus.TransportRTU), "rtu, ascii or tcp")
+	baud := flags.Int("baud", 9600, "serial baud rate")
+	slaveID := flags.Uint("slave", 1, "modbus slave id")
+	timeout := flags.Duration("timeout", 0, "response timeout (default 1s)")
+	formatName := flags.String("format", string(formatTable), "table, json or csv")
+	verbose := flags.Bool("v", false, "log modbus frames")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	f, err := parseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("missing command")
+	}
+
+	config := gorenogymodbus.ClientConfig{
+		Address:   *port,
+		Transport: gorenogymodbus.Transport(*transport),
+		BaudRate:  *baud,
+		SlaveID:   byte(*slaveID),
+		Timeout:   *timeout,
+	}
+	if *verbose {
+		config.Logger = log.New(os.Stderr, "", log.LstdFlags)
+	}
+
+	command, commandArgs := flags.Arg(0), flags.Args()[1:]
+	if command == "profiles" {
+		return listProfiles(stdout, f)
+	}
+	switch command {
+	case "read", "product", "settings", "history", "watch", "backup", "diff", "restore", "apply":
+	default:
+		return fmt.Errorf("unknown command: %s", command)
+	}
+
+	mc, err := gorenogymodbus.NewModbusClientWithConfig(config)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "read":
+		res, err := mc.ReadData()
+		if err != nil {
+			return err
+		}
+		dci, err := gorenogymodbus.Parse(res)
+		if err != nil {
+			return err
+		}
+		return render(stdout, f, dci)
+	case "product":
+		pi, err := mc.ReadProductInformation()
+		if err != nil {
+			return err
+		}
+		return render(stdout, f, pi)
+	case "settings":
+		settings, err := mc.ReadSettings()
+		if err != nil {
+			return err
+		}
+		return render(stdout, f, settings)
+	case "history":
+		return history(mc, commandArgs, stdout, f)
+	case "watch":
+		return watch(mc, commandArgs, stdout)
+	case "backup":
+		return backupSettings(mc, commandArgs, stdout)
+	case "diff":
+		return diffSettings(mc, commandArgs, stdout, f)
+	case "restore":
+		return restoreSettings(mc, commandArgs, stdout)
+	default:
+		return applySettings(mc, commandArgs, stdout, f)
+	}
+}
+
+// historyDay is a day of history numbered like ReadDailyHistory, 0 being today.
+type historyDay struct {
+	Day int `json:"day"`
+	*gorenogymodbus.DailyTotals
+}
+
+func history(mc *gorenogymodbus.ModbusClient, args []string, stdout io.Writer, f format) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	days := flags.Int("days", 1, fmt.Sprintf("number of days to read, at most %d", gorenogymodbus.MaximumHistoryDays))
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *days < 1 || *days > gorenogymodbus.MaximumHistoryDays {
+		return fmt.Errorf("invalid number of days: %d", *days)
+	}
+
+	records := make([]interface{}, 0, *days)
+	for day := 0; day < *days; day++ {
+		dt, err := mc.ReadDailyHistory(day)
+		if err != nil {
+			return fmt.Errorf("failed to read day %d: %w", day, err)
+		}
+		records = append(records, &historyDay{Day: day, DailyTotals: dt})
+	}
+
+	return render(stdout, f, records...)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/simulator"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	records := []interface{}{
+		&historyDay{Day: 0, DailyTotals: &gorenogymodbus.DailyTotals{PowerGeneration: decimal.NewFromFloat(1.5)}},
+		&historyDay{Day: 1, DailyTotals: &gorenogymodbus.DailyTotals{PowerGeneration: decimal.NewFromFloat(0.25)}},
+	}
+
+	tests := []struct {
+		name     string
+		format   format
+		records  []interface{}
+		expected string
+	}{
+		{
+			name:    "table",
+			format:  formatTable,
+			records: records[:1],
+			expected: "day                          0\n" +
+				"battery_minimum_voltage      0\n" +
+				"battery_maximum_voltage      0\n" +
+				"maximum_charging_current     0\n" +
+				"maximum_discharging_current  0\n" +
+				"maximum_charging_power       0\n" +
+				"maximum_discharging_power    0\n" +
+				"charging_amp_hours           0\n" +
+				"discharging_amp_hours        0\n" +
+				"power_generation             1.5\n" +
+				"power_consumption            0\n",
+		},
+		{
+			name:    "csv",
+			format:  formatCSV,
+			records: records,
+			expected: "day,battery_minimum_voltage,battery_maximum_voltage,maximum_charging_current,maximum_discharging_current,maximum_charging_power,maximum_discharging_power,charging_amp_hours,discharging_amp_hours,power_generation,power_consumption\n" +
+				"0,0,0,0,0,0,0,0,0,1.5,0\n" +
+				"1,0,0,0,0,0,0,0,0,0.25,0\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var b bytes.Buffer
+			require.NoError(t, render(&b, tc.format, tc.records...))
+			assert.Equal(t, tc.expected, b.String())
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	start := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	model := simulator.NewModel(simulator.ModelConfig{Clock: func() time.Time { return start }})
+
+	p, err := simulator.OpenPTY()
+	require.NoError(t, err)
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go simulator.NewServer(1, model).Serve(ctx, p) //nolint:errcheck
+
+	var b bytes.Buffer
+	require.NoError(t, run([]string{"-port", p.Path(), "-format", "json", "read"}, &b))
+	var dci gorenogymodbus.DynamicControllerInformation
+	require.NoError(t, json.Unmarshal(b.Bytes(), &dci))
+	assert.Equal(t, 60, dci.BatteryCapacitySOC)
+
+	b.Reset()
+	require.NoError(t, run([]string{"-port", p.Path(), "product"}, &b))
+	assert.Contains(t, b.String(), "RNG-CTRL-RVR40")
+
+	b.Reset()
+	require.NoError(t, run([]string{"-port", p.Path(), "-format", "csv", "history", "-days", "3"}, &b))
+	assert.Len(t, strings.Split(strings.TrimSpace(b.String()), "\n"), 4)
+
+	assert.EqualError(t, run([]string{"-port", p.Path(), "history", "-days", "31"}, &b), "invalid number of days: 31")
+	assert.EqualError(t, run([]string{"-format", "xml", "read"}, &b), "invalid format: xml")
+	assert.EqualError(t, run([]string{"reboot"}, &b), "unknown command: reboot")
+}
+
+// simulate serves a simulated controller at noon on a pseudo-terminal for the
+// duration of a test and returns its path.
+func simulate(t *testing.T) string {
+	start := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	model := simulator.NewModel(simulator.ModelConfig{Clock: func() time.Time { return start }})
+
+	p, err := simulator.OpenPTY()
+	require.NoError(t, err)
+	t.Cleanup(func() { p.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go simulator.NewServer(1, model).Serve(ctx, p) //nolint:errcheck
+
+	return p.Path()
+}
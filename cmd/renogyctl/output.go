@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/shopspring/decimal"
+)
+
+type format string
+
+const (
+	formatTable format = "table"
+	formatJSON  format = "json"
+	formatCSV   format = "csv"
+)
+
+func parseFormat(s string) (format, error) {
+	switch f := format(s); f {
+	case formatTable, formatJSON, formatCSV:
+		return f, nil
+	default:
+		return "", fmt.Errorf("invalid format: %s", s)
+	}
+}
+
+// render prints records, pointers to structs with json tags, in a format. A single
+// record is printed as an object and a field per line rather than as a list.
+func render(w io.Writer, f format, records ...interface{}) error {
+	return renderRecords(w, f, len(records) == 1, records)
+}
+
+// renderList prints records as a list even when there is only one.
+func renderList(w io.Writer, f format, records ...interface{}) error {
+	return renderRecords(w, f, false, records)
+}
+
+func renderRecords(w io.Writer, f format, single bool, records []interface{}) error {
+	switch f {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if single {
+			return enc.Encode(records[0])
+		}
+		if records == nil {
+			records = []interface{}{}
+		}
+		return enc.Encode(records)
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		for i, r := range records {
+			names, values := fields(r)
+			if i == 0 {
+				if err := cw.Write(names); err != nil {
+					return fmt.Errorf("failed to write csv header: %w", err)
+				}
+			}
+			if err := cw.Write(values); err != nil {
+				return fmt.Errorf("failed to write csv row: %w", err)
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		if single {
+			names, values := fields(records[0])
+			for i := range names {
+				fmt.Fprintf(tw, "%s\t%s\n", names[i], values[i])
+			}
+			return tw.Flush()
+		}
+		for i, r := range records {
+			names, values := fields(r)
+			if i == 0 {
+				fmt.Fprintln(tw, strings.Join(names, "\t"))
+			}
+			fmt.Fprintln(tw, strings.Join(values, "\t"))
+		}
+		return tw.Flush()
+	}
+}
+
+// fields returns the json names and formatted values of a struct's fields,
+// flattening embedded structs like encoding/json does.
+func fields(v interface{}) (names []string, values []string) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		value := rv.Field(i)
+		if field.Anonymous {
+			n, v := fields(value.Interface())
+			names = append(names, n...)
+			values = append(values, v...)
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		names = append(names, name)
+		values = append(values, formatValue(value.Interface()))
+	}
+	return names, values
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case decimal.Decimal:
+		return v.String()
+	case []string:
+		return strings.Join(v, ";")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+)
+
+type profileRow struct {
+	Name                         string          `json:"name"`
+	SystemVoltage                int             `json:"system_voltage"`
+	BatteryType                  string          `json:"battery_type"`
+	EqualizingChargingVoltage    decimal.Decimal `json:"equalizing_charging_voltage"`
+	BoostChargingVoltage         decimal.Decimal `json:"boost_charging_voltage"`
+	FloatingChargingVoltage      decimal.Decimal `json:"floating_charging_voltage"`
+	BoostChargingRecoveryVoltage decimal.Decimal `json:"boost_charging_recovery_voltage"`
+	OverDischargeVoltage         decimal.Decimal `json:"over_discharge_voltage"`
+	Description                  string          `json:"description"`
+}
+
+// listProfiles prints the main parameters of the preset profiles, voltages on a
+// 12V basis like the settings.
+func listProfiles(stdout io.Writer, f format) error {
+	var records []interface{}
+	for _, p := range gorenogymodbus.Profiles() {
+		s := p.Settings
+		records = append(records, &profileRow{
+			Name:                         p.Name,
+			SystemVoltage:                s.SystemVoltage,
+			BatteryType:                  s.BatteryType,
+			EqualizingChargingVoltage:    s.EqualizingChargingVoltage,
+			BoostChargingVoltage:         s.BoostChargingVoltage,
+			FloatingChargingVoltage:      s.FloatingChargingVoltage,
+			BoostChargingRecoveryVoltage: s.BoostChargingRecoveryVoltage,
+			OverDischargeVoltage:         s.OverDischargeVoltage,
+			Description:                  p.Description,
+		})
+	}
+	return renderList(stdout, f, records...)
+}
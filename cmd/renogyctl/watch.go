@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"golang.org/x/term"
+)
+
+const (
+	barWidth       = 30
+	sparklineWidth = 40
+
+	ansiHome      = "\x1b[H"
+	ansiClearLine = "\x1b[K"
+	ansiClearDown = "\x1b[J"
+	ansiBold      = "\x1b[1m"
+	ansiRed       = "\x1b[1;31m"
+	ansiReset     = "\x1b[0m"
+)
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+func watch(mc *gorenogymodbus.ModbusClient, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	interval := flags.Duration("interval", 5*time.Second, "time between polls")
+	window := flags.Duration("window", 10*time.Minute, "time covered by the sparklines")
+	count := flags.Int("count", 0, "stop after this many polls, 0 runs until interrupted")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("invalid interval: %s", *interval)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	d := newDashboard(*window)
+	tty := isTerminal(stdout)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for polls := 1; ; polls++ {
+		now := time.Now()
+		dci, err := readDynamic(mc)
+		if err == nil {
+			d.add(now, dci)
+		}
+
+		if tty {
+			fmt.Fprint(stdout, ansiHome+d.frame(now, dci, err)+ansiClearDown)
+		} else {
+			fmt.Fprintln(stdout, line(now, dci, err))
+		}
+
+		if *count > 0 && polls >= *count {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if tty {
+				fmt.Fprintln(stdout)
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func readDynamic(mc *gorenogymodbus.ModbusClient) (*gorenogymodbus.DynamicControllerInformation, error) {
+	res, err := mc.ReadData()
+	if err != nil {
+		return nil, err
+	}
+	return gorenogymodbus.Parse(res)
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+type sample struct {
+	t          time.Time
+	soc        float64
+	voltage    float64
+	solarPower float64
+	loadPower  float64
+}
+
+// dashboard keeps the samples of the sparkline window and draws frames.
+type dashboard struct {
+	window  time.Duration
+	samples []sample
+}
+
+func newDashboard(window time.Duration) *dashboard {
+	return &dashboard{window: window}
+}
+
+func (d *dashboard) add(t time.Time, dci *gorenogymodbus.DynamicControllerInformation) {
+	d.samples = append(d.samples, sample{
+		t:          t,
+		soc:        float64(dci.BatteryCapacitySOC),
+		voltage:    dci.BatteryVoltage.InexactFloat64(),
+		solarPower: dci.ChargingPower.InexactFloat64(),
+		loadPower:  dci.StreetLightLoadPower.InexactFloat64(),
+	})
+
+	cutoff := t.Add(-d.window)
+	i := 0
+	for i < len(d.samples) && d.samples[i].t.Before(cutoff) {
+		i++
+	}
+	d.samples = d.samples[i:]
+}
+
+// frame draws the dashboard of the latest reading, dci is nil when the poll failed.
+func (d *dashboard) frame(now time.Time, dci *gorenogymodbus.DynamicControllerInformation, err error) string {
+	var b strings.Builder
+	writeLine := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, format, args...)
+		b.WriteString(ansiClearLine + "\n")
+	}
+
+	writeLine("%srenogyctl watch%s  %s", ansiBold, ansiReset, now.Format(time.TimeOnly))
+	writeLine("")
+	if err != nil {
+		writeLine("%spoll failed: %v%s", ansiRed, err, ansiReset)
+		writeLine("")
+	}
+	if dci == nil {
+		return b.String()
+	}
+
+	voltage := dci.BatteryVoltage.InexactFloat64()
+	low, high := voltageRange(voltage)
+	writeLine("battery  %s %3d%%", bar(float64(dci.BatteryCapacitySOC), 0, 100, barWidth), dci.BatteryCapacitySOC)
+	writeLine("voltage  %s %5sV", bar(voltage, low, high, barWidth), dci.BatteryVoltage.StringFixed(1))
+	writeLine("solar    %5sW  %sV %sA", dci.ChargingPower, dci.SolarPanelVoltage.StringFixed(1), dci.SolarPanelCurrent.StringFixed(2))
+	load := "off"
+	if dci.StreetLightStatus {
+		load = "on"
+	}
+	writeLine("load     %5sW  %s", dci.StreetLightLoadPower, load)
+	writeLine("state    %s", dci.ChargingState)
+	if len(dci.ControllerFaults) == 0 {
+		writeLine("faults   none")
+	} else {
+		writeLine("faults   %s%s%s", ansiRed, strings.Join(dci.ControllerFaults, ", "), ansiReset)
+	}
+
+	writeLine("")
+	writeLine("last %s", d.window)
+	writeLine("soc      %s", sparkline(d.series(func(s sample) float64 { return s.soc }), sparklineWidth))
+	writeLine("voltage  %s", sparkline(d.series(func(s sample) float64 { return s.voltage }), sparklineWidth))
+	writeLine("solar    %s", sparkline(d.series(func(s sample) float64 { return s.solarPower }), sparklineWidth))
+	writeLine("load     %s", sparkline(d.series(func(s sample) float64 { return s.loadPower }), sparklineWidth))
+
+	return b.String()
+}
+
+func (d *dashboard) series(value func(sample) float64) []float64 {
+	values := make([]float64, len(d.samples))
+	for i, s := range d.samples {
+		values[i] = value(s)
+	}
+	return values
+}
+
+// line is the plain output of a poll when stdout is not a terminal.
+func line(now time.Time, dci *gorenogymodbus.DynamicControllerInformation, err error) string {
+	ts := now.Format(time.RFC3339)
+	if err != nil {
+		return fmt.Sprintf("%s error=%q", ts, err.Error())
+	}
+
+	faults := "none"
+	if len(dci.ControllerFaults) > 0 {
+		faults = strings.Join(dci.ControllerFaults, ";")
+	}
+	return fmt.Sprintf("%s soc=%d%% battery=%sV solar=%sW load=%sW state=%q faults=%q",
+		ts, dci.BatteryCapacitySOC, dci.BatteryVoltage.StringFixed(1), dci.ChargingPower, dci.StreetLightLoadPower, dci.ChargingState, faults)
+}
+
+// voltageRange returns the span of the voltage bar for the 12V multiple a battery
+// voltage belongs to.
+func voltageRange(v float64) (float64, float64) {
+	n := math.Max(1, math.Round(v/13))
+	return 10.5 * n, 15 * n
+}
+
+func bar(v, low, high float64, width int) string {
+	filled := 0
+	if high > low {
+		filled = int(math.Round((v - low) / (high - low) * float64(width)))
+	}
+	if filled < 0 {
+		filled = 0
+	}
+	if filled > width {
+		filled = width
+	}
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+// sparkline draws at most width values, averaging neighbours when there are more.
+func sparkline(values []float64, width int) string {
+	if len(values) == 0 {
+		return ""
+	}
+	if len(values) > width {
+		buckets := make([]float64, width)
+		for i := range buckets {
+			from, to := i*len(values)/width, (i+1)*len(values)/width
+			var sum float64
+			for _, v := range values[from:to] {
+				sum += v
+			}
+			buckets[i] = sum / float64(to-from)
+		}
+		values = buckets
+	}
+
+	min, max := values[0], values[0]
+	for _, v := range values {
+		min, max = math.Min(min, v), math.Max(max, v)
+	}
+
+	runes := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if max > min {
+			level = int((v - min) / (max - min) * float64(len(sparks)-1))
+		}
+		runes[i] = sparks[level]
+	}
+	return string(runes)
+}
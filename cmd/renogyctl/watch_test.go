@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/simulator"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBar(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    float64
+		expected string
+	}{
+		{name: "empty", value: 0, expected: "░░░░"},
+		{name: "half", value: 50, expected: "██░░"},
+		{name: "full", value: 100, expected: "████"},
+		{name: "over", value: 120, expected: "████"},
+		{name: "under", value: -5, expected: "░░░░"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, bar(tc.value, 0, 100, 4))
+		})
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []float64
+		width    int
+		expected string
+	}{
+		{name: "empty", width: 4, expected: ""},
+		{name: "flat", values: []float64{3, 3, 3}, width: 4, expected: "▁▁▁"},
+		{name: "rising", values: []float64{0, 1, 2, 3, 4, 5, 6, 7}, width: 8, expected: "▁▂▃▄▅▆▇█"},
+		{name: "averaged", values: []float64{0, 0, 7, 7}, width: 2, expected: "▁█"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, sparkline(tc.values, tc.width))
+		})
+	}
+}
+
+func TestVoltageRange(t *testing.T) {
+	low, high := voltageRange(13.2)
+	assert.Equal(t, []float64{10.5, 15}, []float64{low, high})
+	low, high = voltageRange(26.8)
+	assert.Equal(t, []float64{21, 30}, []float64{low, high})
+}
+
+func TestDashboard(t *testing.T) {
+	now := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	dci := &gorenogymodbus.DynamicControllerInformation{
+		BatteryCapacitySOC:   80,
+		BatteryVoltage:       decimal.NewFromFloat(13.4),
+		ChargingPower:        decimal.NewFromInt(120),
+		StreetLightLoadPower: decimal.NewFromInt(0),
+		ChargingState:        gorenogymodbus.MPPTChargingMode.String(),
+		ControllerFaults:     []string{gorenogymodbus.BatteryOverVoltage.String()},
+	}
+
+	d := newDashboard(time.Minute)
+	d.add(now.Add(-2*time.Minute), dci)
+	d.add(now, dci)
+	assert.Len(t, d.samples, 1)
+
+	frame := d.frame(now, dci, nil)
+	assert.Contains(t, frame, "80%")
+	assert.Contains(t, frame, "13.4V")
+	assert.Contains(t, frame, ansiRed+"battery over voltage"+ansiReset)
+
+	frame = d.frame(now, nil, errors.New("timeout"))
+	assert.Contains(t, frame, "poll failed: timeout")
+
+	assert.Equal(t, `2023-08-10T12:00:00Z soc=80% battery=13.4V solar=120W load=0W state="mppt charging mode" faults="battery over voltage"`, line(now, dci, nil))
+	assert.Equal(t, `2023-08-10T12:00:00Z error="timeout"`, line(now, nil, errors.New("timeout")))
+}
+
+func TestWatchPlain(t *testing.T) {
+	start := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	model := simulator.NewModel(simulator.ModelConfig{Clock: func() time.Time { return start }})
+
+	p, err := simulator.OpenPTY()
+	require.NoError(t, err)
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go simulator.NewServer(1, model).Serve(ctx, p) //nolint:errcheck
+
+	var b bytes.Buffer
+	require.NoError(t, run([]string{"-port", p.Path(), "watch", "-interval", "10ms", "-count", "2"}, &b))
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "soc=60%")
+	assert.NotContains(t, b.String(), "\x1b[")
+}
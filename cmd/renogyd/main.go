@@ -0,0 +1,130 @@
+// Command renogyd owns the serial port of a Renogy charge controller and serves its
+// state and control over an HTTP JSON API, so several applications can share one
+// controller.
+//
+//	renogyd [flags]
+//
+// The API:
+//
+//	GET  /api/reading              latest reading with its time, latency and device
+//	GET  /api/product              product information
+//	GET  /api/settings             battery and load settings
+//	PUT  /api/settings[?dry_run=1] change settings, a json object of the settings to change
+//	GET  /api/history[?days=n]     daily history, today first
+//	GET  /api/faults               active controller faults
+//	POST /api/load                 switch the load, {"on": true} or {"on": false}
+//	GET  /api/events               server-sent "reading" and "fault" events
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/poller"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "renogyd:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stderr io.Writer) error {
+	flags := flag.NewFlagSet("renogyd", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	port := flags.String("port", "/dev/ttyUSB0", "serial device, or host:port for the tcp transport")
+	transport := flags.String("transport", string(gorenogymodbus.TransportRTU), "rtu, ascii or tcp")
+	baud := flags.Int("baud", 9600, "serial baud rate")
+	slaveID := flags.Uint("slave", 1, "modbus slave id")
+	timeout := flags.Duration("timeout", 0, "response timeout (default 1s)")
+	listen := flags.String("listen", ":8080", "address to serve the api on")
+	interval := flags.Duration("interval", 5*time.Second, "how often to read the controller")
+	settingsInterval := flags.Duration("settings-interval", 15*time.Minute, "how often to read the settings")
+	historyInterval := flags.Duration("history-interval", 24*time.Hour, "how often to read the daily history")
+	faultDebounce := flags.Duration("fault-debounce", 30*time.Second, "how long a fault must persist to be raised or cleared")
+	verbose := flags.Bool("v", false, "log modbus frames")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	logger := log.New(stderr, "renogyd: ", log.LstdFlags)
+	config := gorenogymodbus.ClientConfig{
+		Address:   *port,
+		Transport: gorenogymodbus.Transport(*transport),
+		BaudRate:  *baud,
+		SlaveID:   byte(*slaveID),
+		Timeout:   *timeout,
+	}
+	if *verbose {
+		config.Logger = logger
+	}
+
+	mc, err := gorenogymodbus.NewModbusClientWithConfig(config)
+	if err != nil {
+		return err
+	}
+
+	// product information first, it identifies the device of the readings
+	p, err := poller.New(mc, poller.Config{
+		Schedules: []poller.Schedule{
+			{Block: poller.Product, Interval: time.Hour},
+			{Block: poller.Dynamic, Interval: *interval},
+			{Block: poller.Settings, Interval: *settingsInterval},
+			{Block: poller.History, Interval: *historyInterval},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	s := newServer(p, *faultDebounce, logger)
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	srv := &http.Server{
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		// ends event streams on shutdown
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	go p.Run(ctx) //nolint:errcheck
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(l)
+	}()
+	logger.Printf("serving on %s", l.Addr())
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to shut down: %w", err)
+	}
+	return nil
+}
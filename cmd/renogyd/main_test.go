@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	err := run(context.Background(), []string{"-transport", "carrier pigeon"}, io.Discard)
+	assert.EqualError(t, err, "invalid transport: carrier pigeon")
+
+	err = run(context.Background(), []string{"-interval"}, io.Discard)
+	assert.EqualError(t, err, "flag needs an argument: -interval")
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/poller"
+	"github.com/michaelpeterswa/go-renogy-modbus/reconcile"
+)
+
+const (
+	maximumRequestBody = 1 << 20
+	keepaliveInterval  = 15 * time.Second
+	eventBuffer        = 16
+)
+
+// server serves the latest results of a poller, which it shares the bus with for
+// writes.
+type server struct {
+	poller *poller.Poller
+	logger *log.Logger
+
+	mu       sync.Mutex
+	reading  *gorenogymodbus.Reading
+	product  *gorenogymodbus.ProductInformation
+	settings *gorenogymodbus.Settings
+	history  []*gorenogymodbus.DailyTotals
+	monitor  *gorenogymodbus.FaultMonitor
+	faults   map[gorenogymodbus.ControllerFault]gorenogymodbus.FaultEvent
+	clients  map[chan event]struct{}
+}
+
+// event is a server-sent event.
+type event struct {
+	name string
+	data []byte
+}
+
+// historyDay is a day of history numbered like ReadDailyHistory, 0 being today.
+type historyDay struct {
+	Day int `json:"day"`
+	*gorenogymodbus.DailyTotals
+}
+
+type loadRequest struct {
+	On *bool `json:"on"`
+}
+
+func newServer(p *poller.Poller, faultDebounce time.Duration, logger *log.Logger) *server {
+	s := &server{
+		poller:  p,
+		logger:  logger,
+		monitor: gorenogymodbus.NewFaultMonitor(faultDebounce),
+		faults:  make(map[gorenogymodbus.ControllerFault]gorenogymodbus.FaultEvent),
+		clients: make(map[chan event]struct{}),
+	}
+	p.OnResult(s.update)
+	return s
+}
+
+func (s *server) update(r poller.Result) {
+	if r.Err != nil {
+		s.logger.Printf("failed to read %s: %v", r.Block, r.Err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Block {
+	case poller.Product:
+		s.product = r.Product
+	case poller.Settings:
+		s.settings = r.Settings
+	case poller.History:
+		s.history = r.History
+	case poller.Dynamic:
+		reading := &gorenogymodbus.Reading{Time: r.Time, Latency: r.Latency, Data: r.Dynamic}
+		if s.product != nil {
+			reading.Device = gorenogymodbus.DeviceIdentity{
+				Model:         s.product.Model,
+				SerialNumber:  s.product.SerialNumber,
+				DeviceAddress: s.product.DeviceAddress,
+			}
+		}
+		s.reading = reading
+		s.broadcast("reading", reading)
+
+		for _, e := range s.monitor.Add(r.Time, r.Dynamic) {
+			if e.Kind == gorenogymodbus.FaultRaised {
+				s.faults[e.Fault] = e
+			} else {
+				delete(s.faults, e.Fault)
+			}
+			s.broadcast("fault", e)
+		}
+	}
+}
+
+// broadcast must be called with mu held. Events are dropped for clients that are
+// behind.
+func (s *server) broadcast(name string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.logger.Printf("failed to marshal %s event: %v", name, err)
+		return
+	}
+
+	for ch := range s.clients {
+		select {
+		case ch <- event{name: name, data: data}:
+		default:
+		}
+	}
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/reading", s.handleReading)
+	mux.HandleFunc("/api/product", s.handleProduct)
+	mux.HandleFunc("/api/settings", s.handleSettings)
+	mux.HandleFunc("/api/history", s.handleHistory)
+	mux.HandleFunc("/api/faults", s.handleFaults)
+	mux.HandleFunc("/api/load", s.handleLoad)
+	mux.HandleFunc("/api/events", s.handleEvents)
+	return mux
+}
+
+func (s *server) handleReading(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	s.mu.Lock()
+	reading := s.reading
+	s.mu.Unlock()
+
+	writeCached(w, "reading", reading, reading != nil)
+}
+
+func (s *server) handleProduct(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	s.mu.Lock()
+	product := s.product
+	s.mu.Unlock()
+
+	writeCached(w, "product information", product, product != nil)
+}
+
+func (s *server) handleSettings(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet, http.MethodPut) {
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		s.putSettings(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	settings := s.settings
+	s.mu.Unlock()
+
+	writeCached(w, "settings", settings, settings != nil)
+}
+
+// putSettings changes the settings given by their json names, leaving the others as
+// they are. The resulting settings are validated before anything is written, and
+// the written registers are read back. With ?dry_run=true only the report of what
+// would change is returned.
+func (s *server) putSettings(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid dry_run: %s", v))
+			return
+		}
+	}
+
+	var fields map[string]interface{}
+	if err := decodeBody(w, r, &fields); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	doc := &reconcile.Document{Version: reconcile.Version, Settings: fields}
+
+	current, err := s.poller.Read(r.Context(), poller.Settings)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	desired, err := doc.Desired(current.Settings)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := desired.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid settings: %w", err))
+		return
+	}
+
+	var report *reconcile.Report
+	err = s.poller.Exclusive(func(mc *gorenogymodbus.ModbusClient) error {
+		report, err = reconcile.Reconcile(mc, doc, dryRun)
+		return err
+	})
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	if !dryRun && len(report.Writes) > 0 {
+		s.poller.Read(r.Context(), poller.Settings) //nolint:errcheck // logged by update
+	}
+
+	status := http.StatusOK
+	if report.Failed() {
+		status = http.StatusBadGateway
+	}
+	writeJSON(w, status, report)
+}
+
+// handleHistory serves the days of history read last, ?days=n limits them to the
+// last n days.
+func (s *server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	s.mu.Lock()
+	history := s.history
+	s.mu.Unlock()
+
+	days := len(history)
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > gorenogymodbus.MaximumHistoryDays {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid history days: %s", v))
+			return
+		}
+		if n < days {
+			days = n
+		}
+	}
+
+	response := make([]historyDay, 0, days)
+	for day, totals := range history[:days] {
+		response = append(response, historyDay{Day: day, DailyTotals: totals})
+	}
+	writeCached(w, "history", response, history != nil)
+}
+
+// handleFaults serves the raised faults that have not cleared, as their raise events.
+func (s *server) handleFaults(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	s.mu.Lock()
+	known := s.reading != nil
+	faults := make([]gorenogymodbus.FaultEvent, 0, len(s.faults))
+	for _, e := range s.faults {
+		faults = append(faults, e)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(faults, func(i, j int) bool { return faults[i].Fault < faults[j].Fault })
+	writeCached(w, "faults", faults, known)
+}
+
+// handleLoad switches the load with {"on": true} or {"on": false} and reads the
+// controller again so the latest reading reflects it.
+func (s *server) handleLoad(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+
+	var req loadRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.On == nil {
+		writeError(w, http.StatusBadRequest, errors.New("missing on"))
+		return
+	}
+
+	err := s.poller.Exclusive(func(mc *gorenogymodbus.ModbusClient) error {
+		return mc.SetLoad(*req.On)
+	})
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	s.poller.Read(r.Context(), poller.Dynamic) //nolint:errcheck // logged by update
+	writeJSON(w, http.StatusOK, req)
+}
+
+// handleEvents streams "reading" and "fault" events, starting with the latest reading.
+func (s *server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+
+	ch := make(chan event, eventBuffer)
+	s.mu.Lock()
+	s.clients[ch] = struct{}{}
+	latest := s.reading
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if latest != nil {
+		if data, err := json.Marshal(latest); err == nil {
+			writeEvent(w, event{name: "reading", data: data})
+		}
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(keepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-ch:
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e event) error {
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.name, e.data)
+	return err
+}
+
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed: %s", r.Method))
+	return false
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maximumRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("failed to decode request: %w", err)
+	}
+	return nil
+}
+
+// writeCached writes v, or 503 while the controller has not been read yet.
+func writeCached(w http.ResponseWriter, name string, v interface{}, ok bool) {
+	if !ok {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("%s not read yet", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v) //nolint:errcheck
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/poller"
+	"github.com/michaelpeterswa/go-renogy-modbus/reconcile"
+	"github.com/michaelpeterswa/go-renogy-modbus/simulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serve runs a daemon against a simulated controller until the test ends and waits
+// for its first reading.
+func serve(t *testing.T) (*httptest.Server, *simulator.Model) {
+	now := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	m := simulator.NewModel(simulator.ModelConfig{Clock: func() time.Time { return now }})
+	mc := &gorenogymodbus.ModbusClient{Client: simulator.NewServer(1, m).Client()}
+
+	p, err := poller.New(mc, poller.Config{
+		Schedules: []poller.Schedule{
+			{Block: poller.Product, Interval: time.Hour},
+			{Block: poller.Dynamic, Interval: 10 * time.Millisecond},
+			{Block: poller.Settings, Interval: time.Hour},
+			{Block: poller.History, Interval: time.Hour, Days: 3},
+		},
+	})
+	require.NoError(t, err)
+	s := newServer(p, 0, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ts := httptest.NewUnstartedServer(s.handler())
+	ts.Config.BaseContext = func(l net.Listener) context.Context { return ctx }
+	ts.Start()
+	go p.Run(ctx) //nolint:errcheck
+	t.Cleanup(func() {
+		cancel()
+		ts.Close()
+	})
+
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.reading != nil && s.history != nil
+	}, time.Second, 5*time.Millisecond)
+
+	return ts, m
+}
+
+func request(t *testing.T, ts *httptest.Server, method, path, body string) (int, string) {
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	res, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res.StatusCode, string(b)
+}
+
+func TestServerGet(t *testing.T) {
+	ts, _ := serve(t)
+
+	tests := []struct {
+		Name     string
+		Method   string
+		Path     string
+		Status   int
+		Contains []string
+	}{
+		{
+			Name:     "reading",
+			Method:   http.MethodGet,
+			Path:     "/api/reading",
+			Status:   http.StatusOK,
+			Contains: []string{`"serial_number": "20230810"`, `"battery_capacity_soc": 60`, `"latency"`},
+		},
+		{
+			Name:     "product",
+			Method:   http.MethodGet,
+			Path:     "/api/product",
+			Status:   http.StatusOK,
+			Contains: []string{`"model": "RNG-CTRL-RVR40"`},
+		},
+		{
+			Name:     "settings",
+			Method:   http.MethodGet,
+			Path:     "/api/settings",
+			Status:   http.StatusOK,
+			Contains: []string{`"nominal_battery_capacity": 100`},
+		},
+		{
+			Name:     "history",
+			Method:   http.MethodGet,
+			Path:     "/api/history?days=2",
+			Status:   http.StatusOK,
+			Contains: []string{`"day": 0`, `"day": 1`},
+		},
+		{
+			Name:     "invalid history days",
+			Method:   http.MethodGet,
+			Path:     "/api/history?days=31",
+			Status:   http.StatusBadRequest,
+			Contains: []string{`"error": "invalid history days: 31"`},
+		},
+		{
+			Name:     "no faults",
+			Method:   http.MethodGet,
+			Path:     "/api/faults",
+			Status:   http.StatusOK,
+			Contains: []string{"[]"},
+		},
+		{
+			Name:     "method not allowed",
+			Method:   http.MethodPost,
+			Path:     "/api/reading",
+			Status:   http.StatusMethodNotAllowed,
+			Contains: []string{`"error": "method not allowed: POST"`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			status, body := request(t, ts, tc.Method, tc.Path, "")
+			assert.Equal(t, tc.Status, status, body)
+			for _, s := range tc.Contains {
+				assert.Contains(t, body, s)
+			}
+		})
+	}
+
+	_, body := request(t, ts, http.MethodGet, "/api/history", "")
+	var history []historyDay
+	require.NoError(t, json.Unmarshal([]byte(body), &history))
+	assert.Len(t, history, 3)
+}
+
+func TestServerNotReadYet(t *testing.T) {
+	m := simulator.NewModel(simulator.ModelConfig{})
+	p, err := poller.New(&gorenogymodbus.ModbusClient{Client: simulator.NewServer(1, m).Client()}, poller.Config{})
+	require.NoError(t, err)
+	h := newServer(p, 0, log.New(io.Discard, "", 0)).handler()
+
+	tests := []struct {
+		Path  string
+		Error string
+	}{
+		{Path: "/api/reading", Error: `"error": "reading not read yet"`},
+		{Path: "/api/settings", Error: `"error": "settings not read yet"`},
+		{Path: "/api/faults", Error: `"error": "faults not read yet"`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.Path, nil))
+			assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.Error)
+		})
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	ts, m := serve(t)
+	require.NoError(t, m.RaiseFault(gorenogymodbus.BatteryOverVoltage))
+
+	var faults []struct {
+		Kind  string `json:"kind"`
+		Fault string `json:"fault"`
+	}
+	require.Eventually(t, func() bool {
+		_, body := request(t, ts, http.MethodGet, "/api/faults", "")
+		require.NoError(t, json.Unmarshal([]byte(body), &faults))
+		return len(faults) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "raised", faults[0].Kind)
+	assert.Equal(t, gorenogymodbus.BatteryOverVoltage.String(), faults[0].Fault)
+}
+
+func TestServerLoad(t *testing.T) {
+	ts, m := serve(t)
+
+	status, body := request(t, ts, http.MethodPost, "/api/load", `{"on": true}`)
+	assert.Equal(t, http.StatusOK, status, body)
+	assert.True(t, m.Reading().StreetLightStatus)
+
+	_, body = request(t, ts, http.MethodGet, "/api/reading", "")
+	assert.Contains(t, body, `"street_light_status": true`)
+
+	status, body = request(t, ts, http.MethodPost, "/api/load", `{}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, "missing on")
+
+	status, _ = request(t, ts, http.MethodPost, "/api/load", `{"on": "yes"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestServerPutSettings(t *testing.T) {
+	ts, _ := serve(t)
+
+	tests := []struct {
+		Name     string
+		Path     string
+		Body     string
+		Status   int
+		Contains string
+		Capacity string
+	}{
+		{
+			Name:     "invalid",
+			Path:     "/api/settings",
+			Body:     `{"boost_charging_voltage": 20}`,
+			Status:   http.StatusBadRequest,
+			Contains: "invalid settings",
+			Capacity: `"nominal_battery_capacity": 100`,
+		},
+		{
+			Name:     "unknown",
+			Path:     "/api/settings",
+			Body:     `{"bogus": 1}`,
+			Status:   http.StatusBadRequest,
+			Contains: "unknown setting: bogus",
+			Capacity: `"nominal_battery_capacity": 100`,
+		},
+		{
+			Name:     "register resolution",
+			Path:     "/api/settings",
+			Body:     `{"boost_charging_voltage": 14.44}`,
+			Status:   http.StatusOK,
+			Contains: `"status": "compliant"`,
+			Capacity: `"boost_charging_voltage": "14.4"`,
+		},
+		{
+			Name:     "dry run",
+			Path:     "/api/settings?dry_run=true",
+			Body:     `{"nominal_battery_capacity": 200}`,
+			Status:   http.StatusOK,
+			Contains: `"status": "pending"`,
+			Capacity: `"nominal_battery_capacity": 100`,
+		},
+		{
+			Name:     "changed",
+			Path:     "/api/settings",
+			Body:     `{"nominal_battery_capacity": 200}`,
+			Status:   http.StatusOK,
+			Contains: `"status": "changed"`,
+			Capacity: `"nominal_battery_capacity": 200`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			status, body := request(t, ts, http.MethodPut, tc.Path, tc.Body)
+			assert.Equal(t, tc.Status, status, body)
+			assert.Contains(t, body, tc.Contains)
+
+			_, body = request(t, ts, http.MethodGet, "/api/settings", "")
+			assert.Contains(t, body, tc.Capacity)
+		})
+	}
+
+	_, body := request(t, ts, http.MethodPut, "/api/settings", `{"nominal_battery_capacity": 200}`)
+	var report reconcile.Report
+	require.NoError(t, json.Unmarshal([]byte(body), &report))
+	assert.Equal(t, 1, report.Count(reconcile.StatusCompliant))
+	assert.Empty(t, report.Writes)
+}
+
+func TestServerReadingLatency(t *testing.T) {
+	ts, _ := serve(t)
+
+	_, body := request(t, ts, http.MethodGet, "/api/reading", "")
+	var reading gorenogymodbus.Reading
+	require.NoError(t, json.Unmarshal([]byte(body), &reading))
+	assert.Greater(t, reading.Latency, time.Duration(0))
+}
+
+func TestServerEvents(t *testing.T) {
+	ts, _ := serve(t)
+
+	res, err := ts.Client().Get(ts.URL + "/api/events")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	r := bufio.NewReader(res.Body)
+	for i := 0; i < 2; i++ {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "event: reading\n", line)
+
+		line, err = r.ReadString('\n')
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(line, "data: "), line)
+		var reading gorenogymodbus.Reading
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &reading))
+		assert.Equal(t, "20230810", reading.Device.SerialNumber)
+
+		line, err = r.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "\n", line)
+	}
+}
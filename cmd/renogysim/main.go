@@ -0,0 +1,113 @@
+// Command renogysim simulates a Renogy Rover charge controller on a pseudo-terminal.
+// Point a client at the printed device path as if it were the controller's serial port.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/simulator"
+)
+
+func main() {
+	slaveID := flag.Uint("slave", 1, "modbus slave id to answer")
+	link := flag.String("link", "", "symlink to create to the pseudo-terminal device")
+	speed := flag.Float64("speed", 1, "simulated seconds per second")
+	peak := flag.Float64("peak-watts", 400, "solar power at noon in watts")
+	capacity := flag.Int("capacity", 100, "battery capacity in amp hours")
+	soc := flag.Float64("soc", 60, "initial battery state of charge in percent")
+	loadWatts := flag.Float64("load-watts", 20, "load power in watts")
+	loadOn := flag.Bool("load", false, "start with the load switched on")
+	drop := flag.Float64("drop", 0, "probability of dropping a response")
+	corrupt := flag.Float64("corrupt", 0, "probability of corrupting a response checksum")
+	truncate := flag.Float64("truncate", 0, "probability of truncating a response")
+	delay := flag.Duration("delay", 0, "delay before every response")
+	faults := flag.String("faults", "", "comma separated controller faults to raise, e.g. \"battery over voltage\"")
+	verbose := flag.Bool("v", false, "log requests that fail")
+	flag.Parse()
+
+	logger := log.New(os.Stderr, "renogysim: ", log.LstdFlags)
+
+	model := simulator.NewModel(simulator.ModelConfig{
+		TimeScale:       *speed,
+		PeakSolarPower:  *peak,
+		BatteryCapacity: *capacity,
+		InitialSOC:      *soc,
+		LoadPower:       *loadWatts,
+		LoadOn:          *loadOn,
+	})
+
+	for _, name := range strings.Split(*faults, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		fault, err := parseFault(name)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if err := model.RaiseFault(fault); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	injector := simulator.NewInjector(time.Now().UnixNano())
+	for _, r := range []simulator.Rule{
+		{Injection: simulator.DropResponse, Probability: *drop},
+		{Injection: simulator.CorruptChecksum, Probability: *corrupt},
+		{Injection: simulator.TruncateResponse, Probability: *truncate},
+	} {
+		if r.Probability > 0 {
+			injector.Add(r)
+		}
+	}
+	if *delay > 0 {
+		injector.Add(simulator.Rule{Injection: simulator.DelayResponse, Delay: *delay})
+	}
+
+	p, err := simulator.OpenPTY()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer p.Close()
+
+	path := p.Path()
+	if *link != "" {
+		os.Remove(*link)
+		if err := os.Symlink(path, *link); err != nil {
+			logger.Fatalf("failed to create symlink: %v", err)
+		}
+		defer os.Remove(*link)
+		path = *link
+	}
+	fmt.Println(path)
+
+	server := simulator.NewServer(byte(*slaveID), model)
+	server.Injector = injector
+	if *verbose {
+		server.Logger = logger
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := server.Serve(ctx, p); err != nil && ctx.Err() == nil {
+		logger.Print(err)
+	}
+}
+
+func parseFault(name string) (gorenogymodbus.ControllerFault, error) {
+	for _, fault := range gorenogymodbus.ControllerFaultsMap {
+		if fault.String() == name {
+			return fault, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid controller fault: %s", name)
+}
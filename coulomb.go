@@ -0,0 +1,140 @@
+package gorenogymodbus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CoulombCounterState is the persistent state of a CoulombCounter. It is safe to
+// marshal to json and restore with NewCoulombCounter after a restart.
+type CoulombCounterState struct {
+	SOC               decimal.Decimal `json:"soc"`                  // percent
+	Synced            bool            `json:"synced"`               // resynced to full at least once
+	LastSync          time.Time       `json:"last_sync"`            // last resync to full
+	AmpHoursSinceSync decimal.Decimal `json:"amp_hours_since_sync"` // absolute charge throughput since LastSync
+	LastReading       time.Time       `json:"last_reading"`         // time of the last reading
+	LastNetCurrent    decimal.Decimal `json:"last_net_current"`     // amperes at LastReading
+	LastChargingState string          `json:"last_charging_state"`  // charging state at LastReading
+}
+
+// CoulombCounter estimates state of charge by integrating battery current over
+// successive readings. It resyncs to 100% whenever the controller enters float
+// charging, which suits lithium banks where the voltage based SOC reported by
+// the controller is unreliable.
+type CoulombCounter struct {
+	// MaxInterval is the longest gap between readings that is integrated. Longer
+	// gaps lose the count until the next resync. The first reading after restoring
+	// a state re-anchors the count instead, if it comes within MaxInterval of the
+	// last one. Defaults to 5 minutes.
+	MaxInterval time.Duration
+	// DriftPerCycle is the fraction of confidence lost for every battery capacity
+	// of charge throughput since the last resync. Defaults to 0.05.
+	DriftPerCycle decimal.Decimal
+
+	capacityAh decimal.Decimal
+	state      *CoulombCounterState
+	restored   bool // no reading added since the state was restored
+}
+
+// CoulombEstimate reports the counted state of charge next to the controller's own.
+type CoulombEstimate struct {
+	SOC           decimal.Decimal `json:"soc"`            // percent
+	ControllerSOC int             `json:"controller_soc"` // percent, as reported in 0x100
+	Confidence    decimal.Decimal `json:"confidence"`     // 0 (unknown) to 1 (just resynced)
+}
+
+// NewCoulombCounter creates a counter for a battery of capacityAh amp hours. state
+// may be nil, in which case the count is seeded from the first reading. Otherwise
+// the count continues from state, re-anchored at the first reading.
+func NewCoulombCounter(capacityAh int, state *CoulombCounterState) (*CoulombCounter, error) {
+	if capacityAh <= 0 {
+		return nil, fmt.Errorf("invalid battery capacity: %d", capacityAh)
+	}
+
+	return &CoulombCounter{
+		MaxInterval:   5 * time.Minute,
+		DriftPerCycle: decimal.NewFromFloat(0.05),
+		capacityAh:    decimal.NewFromInt(int64(capacityAh)),
+		state:         state,
+		restored:      state != nil,
+	}, nil
+}
+
+// Add integrates a reading taken at t and returns the updated estimate.
+func (cc *CoulombCounter) Add(t time.Time, dci *DynamicControllerInformation) CoulombEstimate {
+	netCurrent := dci.ChargingCurrent.Sub(dci.StreetLightLoadCurrent)
+
+	switch {
+	case cc.state == nil:
+		cc.state = &CoulombCounterState{
+			SOC: decimal.NewFromInt(int64(dci.BatteryCapacitySOC)),
+		}
+	case cc.restored && t.After(cc.state.LastReading) && t.Sub(cc.state.LastReading) <= cc.MaxInterval:
+		// restarted, count on from the persisted state
+	case t.After(cc.state.LastReading) && t.Sub(cc.state.LastReading) <= cc.MaxInterval:
+		// trapezoidal integration of the current between the two readings
+		hours := decimal.NewFromFloat(t.Sub(cc.state.LastReading).Hours())
+		ampHours := cc.state.LastNetCurrent.Add(netCurrent).Div(decimal.NewFromInt(2)).Mul(hours)
+
+		cc.state.SOC = clampPercent(cc.state.SOC.Add(ampHours.Div(cc.capacityAh).Mul(decimal.NewFromInt(100))))
+		cc.state.AmpHoursSinceSync = cc.state.AmpHoursSinceSync.Add(ampHours.Abs())
+	default:
+		// the charge moved during the gap is unknown
+		cc.state.Synced = false
+	}
+
+	chargingState := dci.ChargingState
+	if chargingState == FloatingChargingMode.String() && cc.state.LastChargingState != chargingState {
+		cc.state.SOC = decimal.NewFromInt(100)
+		cc.state.Synced = true
+		cc.state.LastSync = t
+		cc.state.AmpHoursSinceSync = decimal.Zero
+	}
+
+	cc.restored = false
+	cc.state.LastReading = t
+	cc.state.LastNetCurrent = netCurrent
+	cc.state.LastChargingState = chargingState
+
+	return CoulombEstimate{
+		SOC:           cc.state.SOC.Round(2),
+		ControllerSOC: dci.BatteryCapacitySOC,
+		Confidence:    cc.confidence(),
+	}
+}
+
+// State returns a copy of the counter state for persisting.
+func (cc *CoulombCounter) State() *CoulombCounterState {
+	if cc.state == nil {
+		return nil
+	}
+
+	state := *cc.state
+	return &state
+}
+
+func (cc *CoulombCounter) confidence() decimal.Decimal {
+	if !cc.state.Synced {
+		return decimal.Zero
+	}
+
+	cycles := cc.state.AmpHoursSinceSync.Div(cc.capacityAh)
+	confidence := decimal.NewFromInt(1).Sub(cycles.Mul(cc.DriftPerCycle))
+	if confidence.IsNegative() {
+		return decimal.Zero
+	}
+
+	return confidence.Round(2)
+}
+
+func clampPercent(d decimal.Decimal) decimal.Decimal {
+	if d.IsNegative() {
+		return decimal.Zero
+	}
+	if d.GreaterThan(decimal.NewFromInt(100)) {
+		return decimal.NewFromInt(100)
+	}
+	return d
+}
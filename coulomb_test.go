@@ -0,0 +1,135 @@
+package gorenogymodbus_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoulombCounter(t *testing.T) {
+	start := time.Date(2023, 8, 10, 8, 0, 0, 0, time.UTC)
+
+	reading := func(soc int, charging float64, load float64, state gorenogymodbus.ChargingState) *gorenogymodbus.DynamicControllerInformation {
+		return &gorenogymodbus.DynamicControllerInformation{
+			BatteryCapacitySOC:     soc,
+			ChargingCurrent:        decimal.NewFromFloat(charging),
+			StreetLightLoadCurrent: decimal.NewFromFloat(load),
+			ChargingState:          state.String(),
+		}
+	}
+
+	cc, err := gorenogymodbus.NewCoulombCounter(100, nil)
+	assert.NoError(t, err)
+
+	// seeded from the controller, one hour of charging at 10A
+	var estimate gorenogymodbus.CoulombEstimate
+	for i := 0; i <= 60; i++ {
+		estimate = cc.Add(start.Add(time.Duration(i)*time.Minute), reading(50, 10, 0, gorenogymodbus.MPPTChargingMode))
+	}
+	assert.Equal(t, 60.0, estimate.SOC.InexactFloat64())
+	assert.Equal(t, 50, estimate.ControllerSOC)
+	assert.True(t, estimate.Confidence.IsZero())
+
+	// entering float resyncs to full
+	start = start.Add(time.Hour)
+	estimate = cc.Add(start.Add(time.Minute), reading(70, 10, 0, gorenogymodbus.FloatingChargingMode))
+	assert.Equal(t, 100.0, estimate.SOC.InexactFloat64())
+	assert.Equal(t, 1.0, estimate.Confidence.InexactFloat64())
+
+	// persist and restore across a restart
+	b, err := json.Marshal(cc.State())
+	assert.NoError(t, err)
+	var state gorenogymodbus.CoulombCounterState
+	assert.NoError(t, json.Unmarshal(b, &state))
+	cc, err = gorenogymodbus.NewCoulombCounter(100, &state)
+	assert.NoError(t, err)
+
+	// one hour discharging at 20A
+	for i := 2; i <= 62; i++ {
+		estimate = cc.Add(start.Add(time.Duration(i)*time.Minute), reading(95, 0, 20, gorenogymodbus.ChargingDeactivated))
+	}
+	assert.InDelta(t, 80.0, estimate.SOC.InexactFloat64(), 0.34)
+	assert.Equal(t, 0.99, estimate.Confidence.InexactFloat64())
+
+	// a gap longer than MaxInterval loses the count
+	estimate = cc.Add(start.Add(3*time.Hour), reading(95, 0, 20, gorenogymodbus.ChargingDeactivated))
+	assert.True(t, estimate.Confidence.IsZero())
+}
+
+func TestCoulombCounterRestoreAfterRestart(t *testing.T) {
+	synced := time.Date(2023, 8, 10, 14, 0, 0, 0, time.UTC)
+	state := &gorenogymodbus.CoulombCounterState{
+		SOC:               decimal.NewFromInt(90),
+		Synced:            true,
+		LastSync:          synced,
+		AmpHoursSinceSync: decimal.NewFromInt(20),
+		LastReading:       synced.Add(time.Hour),
+		LastNetCurrent:    decimal.NewFromInt(-20),
+		LastChargingState: gorenogymodbus.ChargingDeactivated.String(),
+	}
+	cc, err := gorenogymodbus.NewCoulombCounter(100, state)
+	assert.NoError(t, err)
+
+	discharging := &gorenogymodbus.DynamicControllerInformation{
+		BatteryCapacitySOC:     85,
+		ChargingCurrent:        decimal.Zero,
+		StreetLightLoadCurrent: decimal.NewFromInt(20),
+		ChargingState:          gorenogymodbus.ChargingDeactivated.String(),
+	}
+
+	// the daemon was down for two minutes, the count is re-anchored
+	restarted := synced.Add(62 * time.Minute)
+	estimate := cc.Add(restarted, discharging)
+	assert.Equal(t, 90.0, estimate.SOC.InexactFloat64())
+	assert.Equal(t, 0.99, estimate.Confidence.InexactFloat64())
+	assert.True(t, cc.State().Synced)
+
+	// and integrated from there
+	estimate = cc.Add(restarted.Add(3*time.Minute), discharging)
+	assert.Equal(t, 89.0, estimate.SOC.InexactFloat64())
+
+	// later gaps lose it again
+	estimate = cc.Add(restarted.Add(time.Hour), discharging)
+	assert.True(t, estimate.Confidence.IsZero())
+}
+
+func TestCoulombCounterRestoreAfterOutage(t *testing.T) {
+	synced := time.Date(2023, 8, 10, 14, 0, 0, 0, time.UTC)
+	discharging := &gorenogymodbus.DynamicControllerInformation{
+		BatteryCapacitySOC:     85,
+		ChargingCurrent:        decimal.Zero,
+		StreetLightLoadCurrent: decimal.NewFromInt(20),
+		ChargingState:          gorenogymodbus.ChargingDeactivated.String(),
+	}
+
+	tests := []struct {
+		Name string
+		Gap  time.Duration
+	}{
+		{Name: "hours later", Gap: 5 * time.Hour},
+		{Name: "before the last reading", Gap: -time.Minute},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			state := &gorenogymodbus.CoulombCounterState{
+				SOC:               decimal.NewFromInt(90),
+				Synced:            true,
+				LastSync:          synced,
+				LastReading:       synced.Add(time.Hour),
+				LastNetCurrent:    decimal.NewFromInt(-20),
+				LastChargingState: gorenogymodbus.ChargingDeactivated.String(),
+			}
+			cc, err := gorenogymodbus.NewCoulombCounter(100, state)
+			assert.NoError(t, err)
+
+			estimate := cc.Add(synced.Add(time.Hour+test.Gap), discharging)
+			assert.True(t, estimate.Confidence.IsZero())
+			assert.False(t, cc.State().Synced)
+		})
+	}
+}
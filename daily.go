@@ -0,0 +1,228 @@
+package gorenogymodbus
+
+import (
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// DailyTotals are the "current day" fields of a reading.
+type DailyTotals struct {
+	BatteryMinimumVoltage     decimal.Decimal `json:"battery_minimum_voltage"`     // Volts
+	BatteryMaximumVoltage     decimal.Decimal `json:"battery_maximum_voltage"`     // Volts
+	MaximumChargingCurrent    decimal.Decimal `json:"maximum_charging_current"`    // Amperes
+	MaximumDischargingCurrent decimal.Decimal `json:"maximum_discharging_current"` // Amperes
+	MaximumChargingPower      decimal.Decimal `json:"maximum_charging_power"`      // Watts
+	MaximumDischargingPower   decimal.Decimal `json:"maximum_discharging_power"`   // Watts
+	ChargingAmpHours          decimal.Decimal `json:"charging_amp_hours"`          // Amp hours
+	DischargingAmpHours       decimal.Decimal `json:"discharging_amp_hours"`       // Amp hours
+	PowerGeneration           decimal.Decimal `json:"power_generation"`            // Kilowatt/hours
+	PowerConsumption          decimal.Decimal `json:"power_consumption"`           // Kilowatt/hours
+}
+
+// DayClosed is emitted when the controller starts a new day. First and Last are the
+// times of the first and last readings seen for the closed day.
+type DayClosed struct {
+	First  time.Time   `json:"first"`
+	Last   time.Time   `json:"last"`
+	Totals DailyTotals `json:"totals"`
+}
+
+// CounterReset is emitted when a cumulative counter decreases, either because it
+// wrapped around its register size or because it was cleared.
+type CounterReset struct {
+	At       time.Time       `json:"at"`
+	Field    string          `json:"field"`
+	Previous decimal.Decimal `json:"previous"`
+	Current  decimal.Decimal `json:"current"`
+	Wrapped  bool            `json:"wrapped"`
+}
+
+// LifetimeTotals are the cumulative counters with resets and wraps accounted for,
+// so they never decrease.
+type LifetimeTotals struct {
+	TotalOperatingDays         decimal.Decimal `json:"total_operating_days"`
+	TotalBatteryOverDischarges decimal.Decimal `json:"total_battery_over_discharges"`
+	TotalBatteryFullCharges    decimal.Decimal `json:"total_battery_full_charges"`
+	TotalChargingAmpHours      decimal.Decimal `json:"total_charging_amp_hours"`
+	TotalDischargingAmpHours   decimal.Decimal `json:"total_discharging_amp_hours"`
+	CumulativePowerGeneration  decimal.Decimal `json:"cumulative_power_generation"`
+	CumulativePowerConsumption decimal.Decimal `json:"cumulative_power_consumption"`
+}
+
+type cumulativeCounter struct {
+	name    string
+	value   func(dci *DynamicControllerInformation) decimal.Decimal
+	total   func(lt *LifetimeTotals) *decimal.Decimal
+	modulus decimal.Decimal // counter value at which the register wraps to zero
+}
+
+var cumulativeCounters = []cumulativeCounter{
+	{
+		"total_operating_days",
+		func(dci *DynamicControllerInformation) decimal.Decimal {
+			return decimal.NewFromInt(int64(dci.TotalOperatingDays))
+		},
+		func(lt *LifetimeTotals) *decimal.Decimal { return &lt.TotalOperatingDays },
+		decimal.NewFromInt(math.MaxUint16 + 1),
+	},
+	{
+		"total_battery_over_discharges",
+		func(dci *DynamicControllerInformation) decimal.Decimal {
+			return decimal.NewFromInt(int64(dci.TotalBatteryOverDischarges))
+		},
+		func(lt *LifetimeTotals) *decimal.Decimal { return &lt.TotalBatteryOverDischarges },
+		decimal.NewFromInt(math.MaxUint16 + 1),
+	},
+	{
+		"total_battery_full_charges",
+		func(dci *DynamicControllerInformation) decimal.Decimal {
+			return decimal.NewFromInt(int64(dci.TotalBatteryFullCharges))
+		},
+		func(lt *LifetimeTotals) *decimal.Decimal { return &lt.TotalBatteryFullCharges },
+		decimal.NewFromInt(math.MaxUint16 + 1),
+	},
+	{
+		"total_charging_amp_hours",
+		func(dci *DynamicControllerInformation) decimal.Decimal { return dci.TotalChargingAmpHours },
+		func(lt *LifetimeTotals) *decimal.Decimal { return &lt.TotalChargingAmpHours },
+		decimal.NewFromInt(math.MaxUint32 + 1),
+	},
+	{
+		"total_discharging_amp_hours",
+		func(dci *DynamicControllerInformation) decimal.Decimal { return dci.TotalDischargingAmpHours },
+		func(lt *LifetimeTotals) *decimal.Decimal { return &lt.TotalDischargingAmpHours },
+		decimal.NewFromInt(math.MaxUint32 + 1),
+	},
+	{
+		"cumulative_power_generation",
+		func(dci *DynamicControllerInformation) decimal.Decimal { return dci.CumulativePowerGeneration },
+		func(lt *LifetimeTotals) *decimal.Decimal { return &lt.CumulativePowerGeneration },
+		decimal.NewFromInt(math.MaxUint32 + 1).Div(decimal.NewFromInt(10000)), // deciwatt/hour registers
+	},
+	{
+		"cumulative_power_consumption",
+		func(dci *DynamicControllerInformation) decimal.Decimal { return dci.CumulativePowerConsumption },
+		func(lt *LifetimeTotals) *decimal.Decimal { return &lt.CumulativePowerConsumption },
+		decimal.NewFromInt(math.MaxUint32 + 1).Div(decimal.NewFromInt(10000)), // deciwatt/hour registers
+	},
+}
+
+// DailyTracker follows successive readings from one controller. It detects when the
+// controller starts a new day, which happens at dawn rather than midnight, and keeps
+// lifetime totals across counter resets and wraps.
+type DailyTracker struct {
+	previous  *DynamicControllerInformation
+	lastAt    time.Time
+	dayFirst  time.Time
+	lifetime  LifetimeTotals
+	lastValue map[string]decimal.Decimal
+}
+
+func NewDailyTracker() *DailyTracker {
+	return &DailyTracker{
+		lastValue: map[string]decimal.Decimal{},
+	}
+}
+
+// Add consumes a reading taken at t. It returns the closed day if the reading
+// belongs to a new day, and any counter resets it detected.
+func (dt *DailyTracker) Add(t time.Time, dci *DynamicControllerInformation) (*DayClosed, []CounterReset) {
+	var resets []CounterReset
+
+	for _, c := range cumulativeCounters {
+		if !dci.Has(c.name) {
+			continue
+		}
+
+		current := c.value(dci)
+		total := c.total(&dt.lifetime)
+
+		previous, seen := dt.lastValue[c.name]
+		switch {
+		case !seen:
+			*total = total.Add(current)
+		case current.GreaterThanOrEqual(previous):
+			*total = total.Add(current.Sub(previous))
+		default:
+			// a counter in the upper half of its range that drops is assumed to have wrapped
+			wrapped := previous.GreaterThan(c.modulus.Div(decimal.NewFromInt(2)))
+			if wrapped {
+				*total = total.Add(c.modulus.Sub(previous).Add(current))
+			} else {
+				*total = total.Add(current)
+			}
+			resets = append(resets, CounterReset{
+				At:       t,
+				Field:    c.name,
+				Previous: previous,
+				Current:  current,
+				Wrapped:  wrapped,
+			})
+		}
+		dt.lastValue[c.name] = current
+	}
+
+	var closed *DayClosed
+	if dt.previous == nil {
+		dt.dayFirst = t
+	} else if newDay(dt.previous, dci) {
+		closed = &DayClosed{
+			First:  dt.dayFirst,
+			Last:   dt.lastAt,
+			Totals: dailyTotals(dt.previous),
+		}
+		dt.dayFirst = t
+	}
+
+	dt.previous = dci
+	dt.lastAt = t
+
+	return closed, resets
+}
+
+// Lifetime returns the monotonic lifetime totals of the readings seen so far.
+func (dt *DailyTracker) Lifetime() LifetimeTotals {
+	return dt.lifetime
+}
+
+// newDay reports whether current belongs to a later controller day than previous,
+// either because the operating day counter moved on or a daily accumulator went back.
+func newDay(previous *DynamicControllerInformation, current *DynamicControllerInformation) bool {
+	if previous.Has("total_operating_days") && current.Has("total_operating_days") &&
+		current.TotalOperatingDays != previous.TotalOperatingDays {
+		return true
+	}
+
+	for _, f := range []struct {
+		name              string
+		previous, current decimal.Decimal
+	}{
+		{"charging_amp_hours_current_day", previous.ChargingAmpHoursCurrentDay, current.ChargingAmpHoursCurrentDay},
+		{"discharging_amp_hours_current_day", previous.DischargingAmpHoursCurrentDay, current.DischargingAmpHoursCurrentDay},
+		{"power_generation_current_day", previous.PowerGenerationCurrentDay, current.PowerGenerationCurrentDay},
+		{"power_consumption_current_day", previous.PowerConsumptionCurrentDay, current.PowerConsumptionCurrentDay},
+	} {
+		if previous.Has(f.name) && current.Has(f.name) && f.current.LessThan(f.previous) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func dailyTotals(dci *DynamicControllerInformation) DailyTotals {
+	return DailyTotals{
+		BatteryMinimumVoltage:     dci.BatteryMinimumVoltageCurrentDay,
+		BatteryMaximumVoltage:     dci.BatteryMaximumVoltageCurrentDay,
+		MaximumChargingCurrent:    dci.MaximumChargingCurrentCurrentDay,
+		MaximumDischargingCurrent: dci.MaximumDischargingCurrentCurrentDay,
+		MaximumChargingPower:      dci.MaximumChargingPowerCurrentDay,
+		MaximumDischargingPower:   dci.MaximumDischargingPowerCurrentDay,
+		ChargingAmpHours:          dci.ChargingAmpHoursCurrentDay,
+		DischargingAmpHours:       dci.DischargingAmpHoursCurrentDay,
+		PowerGeneration:           dci.PowerGenerationCurrentDay,
+		PowerConsumption:          dci.PowerConsumptionCurrentDay,
+	}
+}
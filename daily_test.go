@@ -0,0 +1,70 @@
+package gorenogymodbus_test
+
+import (
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDailyTracker(t *testing.T) {
+	start := time.Date(2023, 8, 10, 6, 0, 0, 0, time.UTC)
+
+	reading := func(days int, generation float64, chargingAh float64, totalAh float64) *gorenogymodbus.DynamicControllerInformation {
+		return &gorenogymodbus.DynamicControllerInformation{
+			TotalOperatingDays:         days,
+			PowerGenerationCurrentDay:  decimal.NewFromFloat(generation),
+			ChargingAmpHoursCurrentDay: decimal.NewFromFloat(chargingAh),
+			TotalChargingAmpHours:      decimal.NewFromFloat(totalAh),
+		}
+	}
+
+	dt := gorenogymodbus.NewDailyTracker()
+
+	closed, resets := dt.Add(start, reading(12, 0.1, 2, 100))
+	assert.Nil(t, closed)
+	assert.Empty(t, resets)
+
+	closed, _ = dt.Add(start.Add(10*time.Hour), reading(12, 1.2, 40, 138))
+	assert.Nil(t, closed)
+
+	// dawn of the next controller day
+	closed, resets = dt.Add(start.Add(24*time.Hour), reading(13, 0, 0, 138))
+	assert.Empty(t, resets)
+	if assert.NotNil(t, closed) {
+		assert.Equal(t, start, closed.First)
+		assert.Equal(t, start.Add(10*time.Hour), closed.Last)
+		assert.Equal(t, 1.2, closed.Totals.PowerGeneration.InexactFloat64())
+		assert.Equal(t, 40.0, closed.Totals.ChargingAmpHours.InexactFloat64())
+	}
+
+	// cleared counter
+	closed, resets = dt.Add(start.Add(25*time.Hour), reading(13, 0.1, 5, 5))
+	assert.Nil(t, closed)
+	if assert.Len(t, resets, 1) {
+		assert.Equal(t, "total_charging_amp_hours", resets[0].Field)
+		assert.False(t, resets[0].Wrapped)
+	}
+	assert.Equal(t, 143.0, dt.Lifetime().TotalChargingAmpHours.InexactFloat64())
+	assert.Equal(t, 13.0, dt.Lifetime().TotalOperatingDays.InexactFloat64())
+}
+
+func TestDailyTrackerWrap(t *testing.T) {
+	start := time.Date(2023, 8, 10, 6, 0, 0, 0, time.UTC)
+	dt := gorenogymodbus.NewDailyTracker()
+
+	_, resets := dt.Add(start, &gorenogymodbus.DynamicControllerInformation{
+		TotalBatteryFullCharges: 65534,
+	})
+	assert.Empty(t, resets)
+
+	_, resets = dt.Add(start.Add(time.Hour), &gorenogymodbus.DynamicControllerInformation{
+		TotalBatteryFullCharges: 1,
+	})
+	if assert.Len(t, resets, 1) {
+		assert.True(t, resets[0].Wrapped)
+	}
+	assert.Equal(t, 65537.0, dt.Lifetime().TotalBatteryFullCharges.InexactFloat64())
+}
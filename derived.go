@@ -0,0 +1,63 @@
+package gorenogymodbus
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+const (
+	UnitWatts        = "W"
+	UnitKilowattHour = "kWh"
+	UnitAmpHours     = "Ah"
+	UnitPercent      = "%"
+)
+
+// Measurement is a derived value and its unit. Valid is false when the value
+// is undefined, e.g. conversion efficiency at night when there is no solar input.
+type Measurement struct {
+	Value decimal.Decimal `json:"value"`
+	Unit  string          `json:"unit"`
+	Valid bool            `json:"valid"`
+}
+
+type DerivedMetrics struct {
+	SolarPanelPower            Measurement `json:"solar_panel_power"`              // solar panel voltage × solar panel current
+	NetBatteryPower            Measurement `json:"net_battery_power"`              // charging power - load power, negative when discharging
+	ConversionEfficiency       Measurement `json:"conversion_efficiency"`          // charging power / solar panel power
+	NetEnergyCurrentDay        Measurement `json:"net_energy_current_day"`         // power generation - power consumption
+	NetAmpHoursCurrentDay      Measurement `json:"net_amp_hours_current_day"`      // charging amp hours - discharging amp hours
+	DepthOfDischargeCurrentDay Measurement `json:"depth_of_discharge_current_day"` // discharging amp hours / battery capacity
+}
+
+// Derived computes metrics that are not reported by the controller directly.
+// batteryCapacityAh is the nominal battery capacity used for the daily depth of
+// discharge, which is left invalid when it is not positive.
+func (dci *DynamicControllerInformation) Derived(batteryCapacityAh int) DerivedMetrics {
+	solarPanelPower := dci.SolarPanelVoltage.Mul(dci.SolarPanelCurrent)
+
+	dm := DerivedMetrics{
+		SolarPanelPower:            validMeasurement(solarPanelPower, UnitWatts),
+		NetBatteryPower:            validMeasurement(dci.ChargingPower.Sub(dci.StreetLightLoadPower), UnitWatts),
+		ConversionEfficiency:       Measurement{Unit: UnitPercent},
+		NetEnergyCurrentDay:        validMeasurement(dci.PowerGenerationCurrentDay.Sub(dci.PowerConsumptionCurrentDay), UnitKilowattHour),
+		NetAmpHoursCurrentDay:      validMeasurement(dci.ChargingAmpHoursCurrentDay.Sub(dci.DischargingAmpHoursCurrentDay), UnitAmpHours),
+		DepthOfDischargeCurrentDay: Measurement{Unit: UnitPercent},
+	}
+
+	if solarPanelPower.IsPositive() {
+		dm.ConversionEfficiency = validMeasurement(dci.ChargingPower.Div(solarPanelPower).Mul(decimal.NewFromInt(100)), UnitPercent)
+	}
+
+	if batteryCapacityAh > 0 {
+		dm.DepthOfDischargeCurrentDay = validMeasurement(dci.DischargingAmpHoursCurrentDay.Div(decimal.NewFromInt(int64(batteryCapacityAh))).Mul(decimal.NewFromInt(100)), UnitPercent)
+	}
+
+	return dm
+}
+
+func validMeasurement(d decimal.Decimal, unit string) Measurement {
+	return Measurement{
+		Value: d.Round(2),
+		Unit:  unit,
+		Valid: true,
+	}
+}
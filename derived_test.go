@@ -0,0 +1,72 @@
+package gorenogymodbus_test
+
+import (
+	"testing"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDerived(t *testing.T) {
+	tests := []struct {
+		Name                 string
+		DCI                  gorenogymodbus.DynamicControllerInformation
+		BatteryCapacityAh    int
+		NetBatteryPower      float64
+		ConversionEfficiency *float64
+		NetEnergyCurrentDay  float64
+		DepthOfDischarge     *float64
+	}{
+		{
+			Name: "daytime charging",
+			DCI: gorenogymodbus.DynamicControllerInformation{
+				StreetLightLoadPower:          decimal.NewFromFloat(12),
+				SolarPanelVoltage:             decimal.NewFromFloat(20),
+				SolarPanelCurrent:             decimal.NewFromFloat(5),
+				ChargingPower:                 decimal.NewFromFloat(95),
+				PowerGenerationCurrentDay:     decimal.NewFromFloat(0.5),
+				PowerConsumptionCurrentDay:    decimal.NewFromFloat(0.2),
+				DischargingAmpHoursCurrentDay: decimal.NewFromFloat(10),
+			},
+			BatteryCapacityAh:    100,
+			NetBatteryPower:      83,
+			ConversionEfficiency: floatPtr(95),
+			NetEnergyCurrentDay:  0.3,
+			DepthOfDischarge:     floatPtr(10),
+		},
+		{
+			Name: "night with unknown capacity",
+			DCI: gorenogymodbus.DynamicControllerInformation{
+				StreetLightLoadPower:       decimal.NewFromFloat(30),
+				PowerConsumptionCurrentDay: decimal.NewFromFloat(0.1),
+			},
+			NetBatteryPower:     -30,
+			NetEnergyCurrentDay: -0.1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			result := tc.DCI.Derived(tc.BatteryCapacityAh)
+
+			assert.Equal(t, gorenogymodbus.UnitWatts, result.NetBatteryPower.Unit)
+			assert.Equal(t, tc.NetBatteryPower, result.NetBatteryPower.Value.InexactFloat64())
+			assert.Equal(t, tc.NetEnergyCurrentDay, result.NetEnergyCurrentDay.Value.InexactFloat64())
+
+			assert.Equal(t, tc.ConversionEfficiency != nil, result.ConversionEfficiency.Valid)
+			if tc.ConversionEfficiency != nil {
+				assert.Equal(t, *tc.ConversionEfficiency, result.ConversionEfficiency.Value.InexactFloat64())
+			}
+
+			assert.Equal(t, tc.DepthOfDischarge != nil, result.DepthOfDischargeCurrentDay.Valid)
+			if tc.DepthOfDischarge != nil {
+				assert.Equal(t, *tc.DepthOfDischarge, result.DepthOfDischargeCurrentDay.Value.InexactFloat64())
+			}
+		})
+	}
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
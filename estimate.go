@@ -0,0 +1,100 @@
+package gorenogymodbus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type currentSample struct {
+	at      time.Time
+	current decimal.Decimal
+}
+
+// BatteryEstimator estimates the time until the battery is full or until the low
+// voltage disconnect from successive readings, smoothing the net battery current
+// over a time window.
+type BatteryEstimator struct {
+	capacityAh    decimal.Decimal
+	disconnectSOC int // state of charge at which the load is disconnected
+	window        time.Duration
+	samples       []currentSample
+	soc           int
+}
+
+// BatteryEstimate is the result of an estimation. TimeToEmpty is the time until the
+// low voltage disconnect. A zero duration means the battery is not moving towards
+// that state.
+type BatteryEstimate struct {
+	NetCurrent  decimal.Decimal `json:"net_current"` // amperes, negative when discharging
+	TimeToFull  time.Duration   `json:"time_to_full"`
+	TimeToEmpty time.Duration   `json:"time_to_empty"`
+}
+
+// NewBatteryEstimator creates an estimator for a battery of capacityAh amp hours, as
+// supplied or read with ReadNominalBatteryCapacity, that is disconnected at
+// disconnectSOC percent, averaging current over window.
+func NewBatteryEstimator(capacityAh int, disconnectSOC int, window time.Duration) (*BatteryEstimator, error) {
+	if capacityAh <= 0 {
+		return nil, fmt.Errorf("invalid battery capacity: %d", capacityAh)
+	}
+	if disconnectSOC <= 0 || disconnectSOC >= 100 {
+		return nil, fmt.Errorf("invalid disconnect soc: %d", disconnectSOC)
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("invalid smoothing window: %s", window)
+	}
+
+	return &BatteryEstimator{
+		capacityAh:    decimal.NewFromInt(int64(capacityAh)),
+		disconnectSOC: disconnectSOC,
+		window:        window,
+	}, nil
+}
+
+// Add records a reading taken at t. Readings older than the window are discarded.
+func (be *BatteryEstimator) Add(t time.Time, dci *DynamicControllerInformation) {
+	be.samples = append(be.samples, currentSample{
+		at:      t,
+		current: dci.ChargingCurrent.Sub(dci.StreetLightLoadCurrent),
+	})
+	be.soc = dci.BatteryCapacitySOC
+
+	cutoff := t.Add(-be.window)
+	i := 0
+	for i < len(be.samples)-1 && !be.samples[i].at.After(cutoff) {
+		i++
+	}
+	be.samples = be.samples[i:]
+}
+
+// Estimate returns the estimate from the readings currently in the window.
+func (be *BatteryEstimator) Estimate() (BatteryEstimate, error) {
+	if len(be.samples) == 0 {
+		return BatteryEstimate{}, fmt.Errorf("no readings to estimate from")
+	}
+
+	sum := decimal.Zero
+	for _, s := range be.samples {
+		sum = sum.Add(s.current)
+	}
+	netCurrent := sum.Div(decimal.NewFromInt(int64(len(be.samples)))).Round(2)
+
+	estimate := BatteryEstimate{NetCurrent: netCurrent}
+
+	switch {
+	case netCurrent.IsPositive() && be.soc < 100:
+		remaining := be.capacityAh.Mul(decimal.NewFromInt(int64(100 - be.soc))).Div(decimal.NewFromInt(100))
+		estimate.TimeToFull = hoursToDuration(remaining.Div(netCurrent))
+	case netCurrent.IsNegative() && be.soc > be.disconnectSOC:
+		remaining := be.capacityAh.Mul(decimal.NewFromInt(int64(be.soc - be.disconnectSOC))).Div(decimal.NewFromInt(100))
+		estimate.TimeToEmpty = hoursToDuration(remaining.Div(netCurrent.Neg()))
+	}
+
+	return estimate, nil
+}
+
+func hoursToDuration(hours decimal.Decimal) time.Duration {
+	return time.Duration(hours.Mul(decimal.NewFromInt(int64(time.Hour))).IntPart())
+}
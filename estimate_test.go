@@ -0,0 +1,98 @@
+package gorenogymodbus_test
+
+import (
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatteryEstimator(t *testing.T) {
+	start := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		Name          string
+		SOC           int
+		Currents      [][2]float64 // charging current, load current
+		DisconnectSOC int
+		NetCurrent    float64
+		TimeToFull    time.Duration
+		TimeToEmpty   time.Duration
+	}{
+		{
+			Name:          "charging",
+			SOC:           50,
+			Currents:      [][2]float64{{10, 0}, {10, 0}},
+			DisconnectSOC: 10,
+			NetCurrent:    10,
+			TimeToFull:    5 * time.Hour,
+		},
+		{
+			Name:          "discharging to disconnect",
+			SOC:           50,
+			Currents:      [][2]float64{{0, 4}, {0, 6}},
+			DisconnectSOC: 10,
+			NetCurrent:    -5,
+			TimeToEmpty:   8 * time.Hour,
+		},
+		{
+			Name:          "old readings fall out of window",
+			SOC:           90,
+			Currents:      [][2]float64{{0, 50}, {0, 50}, {0, 50}, {2, 0}, {2, 0}},
+			DisconnectSOC: 10,
+			NetCurrent:    2,
+			TimeToFull:    5 * time.Hour,
+		},
+		{
+			Name:          "idle",
+			SOC:           80,
+			Currents:      [][2]float64{{1, 1}},
+			DisconnectSOC: 10,
+			NetCurrent:    0,
+		},
+		{
+			Name:          "below disconnect",
+			SOC:           8,
+			Currents:      [][2]float64{{0, 2}},
+			DisconnectSOC: 10,
+			NetCurrent:    -2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			be, err := gorenogymodbus.NewBatteryEstimator(100, tc.DisconnectSOC, 2*time.Minute)
+			assert.NoError(t, err)
+
+			for i, c := range tc.Currents {
+				be.Add(start.Add(time.Duration(i)*time.Minute), &gorenogymodbus.DynamicControllerInformation{
+					BatteryCapacitySOC:     tc.SOC,
+					ChargingCurrent:        decimal.NewFromFloat(c[0]),
+					StreetLightLoadCurrent: decimal.NewFromFloat(c[1]),
+				})
+			}
+
+			result, err := be.Estimate()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.NetCurrent, result.NetCurrent.InexactFloat64())
+			assert.Equal(t, tc.TimeToFull, result.TimeToFull)
+			assert.Equal(t, tc.TimeToEmpty, result.TimeToEmpty)
+		})
+	}
+}
+
+func TestBatteryEstimatorInvalid(t *testing.T) {
+	_, err := gorenogymodbus.NewBatteryEstimator(0, 10, time.Minute)
+	assert.EqualError(t, err, "invalid battery capacity: 0")
+
+	_, err = gorenogymodbus.NewBatteryEstimator(100, 0, time.Minute)
+	assert.EqualError(t, err, "invalid disconnect soc: 0")
+
+	be, err := gorenogymodbus.NewBatteryEstimator(100, 10, time.Minute)
+	assert.NoError(t, err)
+
+	_, err = be.Estimate()
+	assert.Error(t, err)
+}
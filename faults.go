@@ -0,0 +1,132 @@
+package gorenogymodbus
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+type FaultEventKind int
+
+const (
+	FaultRaised FaultEventKind = iota
+	FaultCleared
+)
+
+func (k FaultEventKind) String() string {
+	switch k {
+	case FaultRaised:
+		return "raised"
+	case FaultCleared:
+		return "cleared"
+	default:
+		return "unknown"
+	}
+}
+
+func (k FaultEventKind) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.String())
+}
+
+// FaultEvent is a controller fault appearing or clearing. Since is when the fault was
+// first seen. For a FaultRaised event At is when it was raised, after the debounce,
+// and Duration how long it has been active. For a FaultCleared event At is when the
+// fault was first seen absent and Duration how long it was active.
+type FaultEvent struct {
+	Kind     FaultEventKind  `json:"kind"`
+	Fault    ControllerFault `json:"-"`
+	Name     string          `json:"fault"`
+	Since    time.Time       `json:"since"`
+	At       time.Time       `json:"at"`
+	Duration time.Duration   `json:"duration"`
+}
+
+type faultState struct {
+	since       time.Time // first seen present
+	raised      bool
+	absentSince time.Time // first seen absent since last present, zero while present
+}
+
+// FaultMonitor turns the fault lists of successive readings into raise and clear
+// events. With a debounce a fault is raised once it was first seen at least the
+// debounce ago without having been absent for the debounce since, and cleared once
+// it has been absent for the debounce. A flapping fault raises once and clears once.
+type FaultMonitor struct {
+	Debounce time.Duration
+
+	faults map[ControllerFault]*faultState
+}
+
+func NewFaultMonitor(debounce time.Duration) *FaultMonitor {
+	return &FaultMonitor{
+		Debounce: debounce,
+		faults:   map[ControllerFault]*faultState{},
+	}
+}
+
+// Add feeds a reading taken at t and returns the resulting events ordered by fault.
+// Readings without the fault registers are ignored.
+func (fm *FaultMonitor) Add(t time.Time, dci *DynamicControllerInformation) []FaultEvent {
+	if !dci.Has("controller_faults") {
+		return nil
+	}
+
+	present := map[ControllerFault]bool{}
+	for _, name := range dci.ControllerFaults {
+		if f := controllerFaultFromString(name); f >= 0 {
+			present[f] = true
+		}
+	}
+	for f := range present {
+		if _, ok := fm.faults[f]; !ok {
+			fm.faults[f] = &faultState{since: t}
+		}
+	}
+
+	var events []FaultEvent
+	for _, f := range fm.sortedFaults() {
+		state := fm.faults[f]
+
+		if present[f] {
+			state.absentSince = time.Time{}
+			if !state.raised && t.Sub(state.since) >= fm.Debounce {
+				state.raised = true
+				events = append(events, FaultEvent{Kind: FaultRaised, Fault: f, Name: f.String(), Since: state.since, At: t, Duration: t.Sub(state.since)})
+			}
+			continue
+		}
+
+		if state.absentSince.IsZero() {
+			state.absentSince = t
+		}
+		if t.Sub(state.absentSince) < fm.Debounce {
+			continue
+		}
+		delete(fm.faults, f)
+		if state.raised {
+			events = append(events, FaultEvent{Kind: FaultCleared, Fault: f, Name: f.String(), Since: state.since, At: state.absentSince, Duration: state.absentSince.Sub(state.since)})
+		}
+	}
+
+	return events
+}
+
+// Active returns the raised faults that have not been cleared.
+func (fm *FaultMonitor) Active() []ControllerFault {
+	var active []ControllerFault
+	for _, f := range fm.sortedFaults() {
+		if fm.faults[f].raised {
+			active = append(active, f)
+		}
+	}
+	return active
+}
+
+func (fm *FaultMonitor) sortedFaults() []ControllerFault {
+	faults := make([]ControllerFault, 0, len(fm.faults))
+	for f := range fm.faults {
+		faults = append(faults, f)
+	}
+	sort.Slice(faults, func(i, j int) bool { return faults[i] < faults[j] })
+	return faults
+}
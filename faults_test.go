@@ -0,0 +1,104 @@
+package gorenogymodbus_test
+
+import (
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultMonitor(t *testing.T) {
+	start := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	under := gorenogymodbus.BatteryUnderVoltage
+	over := gorenogymodbus.LoadShortCircuit
+
+	type step struct {
+		seconds  int
+		faults   []gorenogymodbus.ControllerFault
+		expected []gorenogymodbus.FaultEvent
+	}
+
+	raised := func(f gorenogymodbus.ControllerFault, since, at int) gorenogymodbus.FaultEvent {
+		return gorenogymodbus.FaultEvent{Kind: gorenogymodbus.FaultRaised, Fault: f, Name: f.String(), Since: start.Add(time.Duration(since) * time.Second), At: start.Add(time.Duration(at) * time.Second), Duration: time.Duration(at-since) * time.Second}
+	}
+	cleared := func(f gorenogymodbus.ControllerFault, since, at int) gorenogymodbus.FaultEvent {
+		return gorenogymodbus.FaultEvent{Kind: gorenogymodbus.FaultCleared, Fault: f, Name: f.String(), Since: start.Add(time.Duration(since) * time.Second), At: start.Add(time.Duration(at) * time.Second), Duration: time.Duration(at-since) * time.Second}
+	}
+
+	tests := []struct {
+		name     string
+		debounce time.Duration
+		steps    []step
+	}{
+		{
+			name: "no debounce",
+			steps: []step{
+				{seconds: 0},
+				{seconds: 10, faults: []gorenogymodbus.ControllerFault{under, over}, expected: []gorenogymodbus.FaultEvent{raised(over, 10, 10), raised(under, 10, 10)}},
+				{seconds: 20, faults: []gorenogymodbus.ControllerFault{under}, expected: []gorenogymodbus.FaultEvent{cleared(over, 10, 20)}},
+				{seconds: 30, faults: []gorenogymodbus.ControllerFault{under}},
+				{seconds: 40, expected: []gorenogymodbus.FaultEvent{cleared(under, 10, 40)}},
+			},
+		},
+		{
+			name:     "flapping",
+			debounce: 30 * time.Second,
+			steps: []step{
+				{seconds: 0, faults: []gorenogymodbus.ControllerFault{under}},
+				{seconds: 10},
+				{seconds: 20, faults: []gorenogymodbus.ControllerFault{under}},
+				{seconds: 30, faults: []gorenogymodbus.ControllerFault{under}, expected: []gorenogymodbus.FaultEvent{raised(under, 0, 30)}},
+				{seconds: 40},
+				{seconds: 50, faults: []gorenogymodbus.ControllerFault{under}},
+				{seconds: 60},
+				{seconds: 80},
+				{seconds: 90, expected: []gorenogymodbus.FaultEvent{cleared(under, 0, 60)}},
+			},
+		},
+		{
+			name:     "blip",
+			debounce: 30 * time.Second,
+			steps: []step{
+				{seconds: 0, faults: []gorenogymodbus.ControllerFault{under}},
+				{seconds: 10},
+				{seconds: 40},
+				{seconds: 50, faults: []gorenogymodbus.ControllerFault{under}},
+				{seconds: 60},
+				{seconds: 90},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fm := gorenogymodbus.NewFaultMonitor(tc.debounce)
+			for _, s := range tc.steps {
+				var names []string
+				for _, f := range s.faults {
+					names = append(names, f.String())
+				}
+				events := fm.Add(at(s.seconds), &gorenogymodbus.DynamicControllerInformation{ControllerFaults: names})
+				assert.Equal(t, s.expected, events, "at %ds", s.seconds)
+			}
+		})
+	}
+}
+
+func TestFaultMonitorActive(t *testing.T) {
+	start := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	fm := gorenogymodbus.NewFaultMonitor(time.Minute)
+
+	dci := &gorenogymodbus.DynamicControllerInformation{ControllerFaults: []string{gorenogymodbus.BatteryOverVoltage.String()}}
+	fm.Add(start, dci)
+	assert.Empty(t, fm.Active())
+	fm.Add(start.Add(time.Minute), dci)
+	assert.Equal(t, []gorenogymodbus.ControllerFault{gorenogymodbus.BatteryOverVoltage}, fm.Active())
+
+	// readings without the fault registers change nothing
+	partial, err := gorenogymodbus.ParseRange(0x100, sampleBytes[:2])
+	assert.NoError(t, err)
+	assert.Nil(t, fm.Add(start.Add(2*time.Minute), partial))
+	assert.Equal(t, []gorenogymodbus.ControllerFault{gorenogymodbus.BatteryOverVoltage}, fm.Active())
+}
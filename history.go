@@ -0,0 +1,79 @@
+package gorenogymodbus
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	historyAddress  uint16 = 0xF000
+	historyQuantity uint16 = 10
+
+	// MaximumHistoryDays is the number of days of history kept by the controller.
+	MaximumHistoryDays = 30
+)
+
+// ReadDailyHistory reads the historical totals of a day (0xF000 onwards), where day
+// 0 is the current day, 1 the day before and so on.
+func (mc *ModbusClient) ReadDailyHistory(day int) (*DailyTotals, error) {
+	address, err := DailyHistoryAddress(day)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := mc.readHoldingRegisters(address, historyQuantity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read holding registers: %w", err)
+	}
+
+	return ParseDailyHistory(res)
+}
+
+// DailyHistoryAddress returns the first register of a day of history. Each day is
+// ten registers laid out like the current day fields of 0x10B-0x114.
+func DailyHistoryAddress(day int) (uint16, error) {
+	if day < 0 || day >= MaximumHistoryDays {
+		return 0, fmt.Errorf("invalid history day: %d", day)
+	}
+
+	return historyAddress + uint16(day)*historyQuantity, nil
+}
+
+func ParseDailyHistory(dataBytes []byte) (*DailyTotals, error) {
+	if len(dataBytes) != int(historyQuantity)*2 {
+		return nil, fmt.Errorf("data length is not %d bytes: %d", historyQuantity*2, len(dataBytes))
+	}
+
+	return &DailyTotals{
+		BatteryMinimumVoltage:     decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[0:2])) * 0.1),       // battery minimum voltage
+		BatteryMaximumVoltage:     decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[2:4])) * 0.1),       // battery maximum voltage
+		MaximumChargingCurrent:    decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[4:6])) * 0.01),      // maximum charging current
+		MaximumDischargingCurrent: decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[6:8])) * 0.01),      // maximum discharging current
+		MaximumChargingPower:      decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[8:10]))),            // maximum charging power
+		MaximumDischargingPower:   decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[10:12]))),           // maximum discharging power
+		ChargingAmpHours:          decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[12:14]))),           // charging amp hours
+		DischargingAmpHours:       decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[14:16]))),           // discharging amp hours
+		PowerGeneration:           decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[16:18])) / 10000.0), // power generation (deciwatt/hour conversion to kilowatt/hour)
+		PowerConsumption:          decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[18:20])) / 10000.0), // power consumption (deciwatt/hour conversion to kilowatt/hour)
+	}, nil
+}
+
+// Synthesize encodes the totals in the layout accepted by ParseDailyHistory.
+func (dt *DailyTotals) Synthesize() ([]byte, error) {
+	var data []byte
+
+	data = binary.BigEndian.AppendUint16(data, uint16(dt.BatteryMinimumVoltage.Div(decimal.NewFromFloat(0.1)).InexactFloat64()))
+	data = binary.BigEndian.AppendUint16(data, uint16(dt.BatteryMaximumVoltage.Div(decimal.NewFromFloat(0.1)).InexactFloat64()))
+	data = binary.BigEndian.AppendUint16(data, uint16(dt.MaximumChargingCurrent.Div(decimal.NewFromFloat(0.01)).InexactFloat64()))
+	data = binary.BigEndian.AppendUint16(data, uint16(dt.MaximumDischargingCurrent.Div(decimal.NewFromFloat(0.01)).InexactFloat64()))
+	data = binary.BigEndian.AppendUint16(data, uint16(dt.MaximumChargingPower.InexactFloat64()))
+	data = binary.BigEndian.AppendUint16(data, uint16(dt.MaximumDischargingPower.InexactFloat64()))
+	data = binary.BigEndian.AppendUint16(data, uint16(dt.ChargingAmpHours.InexactFloat64()))
+	data = binary.BigEndian.AppendUint16(data, uint16(dt.DischargingAmpHours.InexactFloat64()))
+	data = binary.BigEndian.AppendUint16(data, uint16(dt.PowerGeneration.Mul(decimal.NewFromInt(10000)).InexactFloat64()))
+	data = binary.BigEndian.AppendUint16(data, uint16(dt.PowerConsumption.Mul(decimal.NewFromInt(10000)).InexactFloat64()))
+
+	return data, nil
+}
@@ -0,0 +1,40 @@
+package gorenogymodbus_test
+
+import (
+	"testing"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDailyHistory(t *testing.T) {
+	totals := gorenogymodbus.DailyTotals{
+		BatteryMinimumVoltage:     decimal.NewFromFloat(12.4),
+		BatteryMaximumVoltage:     decimal.NewFromFloat(14.4),
+		MaximumChargingCurrent:    decimal.NewFromFloat(8.25),
+		MaximumDischargingCurrent: decimal.NewFromFloat(2),
+		MaximumChargingPower:      decimal.NewFromFloat(118),
+		MaximumDischargingPower:   decimal.NewFromFloat(26),
+		ChargingAmpHours:          decimal.NewFromFloat(42),
+		DischargingAmpHours:       decimal.NewFromFloat(12),
+		PowerGeneration:           decimal.NewFromFloat(0.55),
+		PowerConsumption:          decimal.NewFromFloat(0.16),
+	}
+
+	b, err := totals.Synthesize()
+	assert.NoError(t, err)
+
+	result, err := gorenogymodbus.ParseDailyHistory(b)
+	assert.NoError(t, err)
+	assert.True(t, totals.MaximumChargingCurrent.Equal(result.MaximumChargingCurrent))
+	assert.True(t, totals.PowerGeneration.Equal(result.PowerGeneration))
+	assert.True(t, totals.PowerConsumption.Equal(result.PowerConsumption))
+
+	address, err := gorenogymodbus.DailyHistoryAddress(2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(0xF014), address)
+
+	_, err = gorenogymodbus.DailyHistoryAddress(gorenogymodbus.MaximumHistoryDays)
+	assert.Error(t, err)
+}
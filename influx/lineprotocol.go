@@ -0,0 +1,100 @@
+// Package influx encodes controller readings as InfluxDB line protocol and writes
+// them to an InfluxDB server in batches.
+package influx
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	keyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	stringEscaper      = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+)
+
+// integerFields are the decimal fields holding whole counters.
+var integerFields = map[string]bool{
+	"total_charging_amp_hours":    true,
+	"total_discharging_amp_hours": true,
+}
+
+// Encode returns the line protocol for a reading taken at t, with a nanosecond
+// timestamp. tags identify the device, e.g. serial and model. Fields are named after
+// the json names of DynamicControllerInformation. Decimal fields are written as
+// floats, counters and other integers as integers. Fields absent from a partial
+// reading are omitted.
+func Encode(measurement string, tags map[string]string, t time.Time, dci *gorenogymodbus.DynamicControllerInformation) ([]byte, error) {
+	var b bytes.Buffer
+
+	b.WriteString(measurementEscaper.Replace(measurement))
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if tags[k] == "" {
+			continue
+		}
+		b.WriteByte(',')
+		b.WriteString(keyEscaper.Replace(k))
+		b.WriteByte('=')
+		b.WriteString(keyEscaper.Replace(tags[k]))
+	}
+
+	fields := 0
+	for _, f := range dci.Fields() {
+		value, err := fieldValue(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode field %s: %w", f.Name, err)
+		}
+
+		if fields == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteByte(',')
+		}
+		b.WriteString(keyEscaper.Replace(f.Name))
+		b.WriteByte('=')
+		b.WriteString(value)
+		fields++
+	}
+
+	if fields == 0 {
+		return nil, fmt.Errorf("reading has no fields")
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatInt(t.UnixNano(), 10))
+
+	return b.Bytes(), nil
+}
+
+func fieldValue(f gorenogymodbus.Field) (string, error) {
+	switch v := f.Value.(type) {
+	case decimal.Decimal:
+		if integerFields[f.Name] {
+			return v.Truncate(0).String() + "i", nil
+		}
+		return strconv.FormatFloat(v.InexactFloat64(), 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(v) + "i", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case string:
+		return `"` + stringEscaper.Replace(v) + `"`, nil
+	case []string:
+		return `"` + stringEscaper.Replace(strings.Join(v, ",")) + `"`, nil
+	}
+
+	return "", fmt.Errorf("unsupported type %T", f.Value)
+}
@@ -0,0 +1,83 @@
+package influx_test
+
+import (
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/influx"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	at := time.Unix(1691625600, 123)
+
+	tests := []struct {
+		Name        string
+		Measurement string
+		Tags        map[string]string
+		DCI         func() *gorenogymodbus.DynamicControllerInformation
+		Contains    []string
+		Line        string
+		ShouldError bool
+	}{
+		{
+			Name:        "full reading",
+			Measurement: "renogy",
+			Tags:        map[string]string{"serial": "1234", "model": "RNG-CTRL-RVR40"},
+			DCI: func() *gorenogymodbus.DynamicControllerInformation {
+				return &gorenogymodbus.DynamicControllerInformation{
+					BatteryCapacitySOC:    100,
+					BatteryVoltage:        decimal.NewFromFloat(13.6),
+					TotalChargingAmpHours: decimal.NewFromInt(1234),
+					ChargingState:         gorenogymodbus.MPPTChargingMode.String(),
+					ControllerFaults:      []string{"battery over voltage", "load short circuit"},
+				}
+			},
+			Contains: []string{
+				"renogy,model=RNG-CTRL-RVR40,serial=1234 battery_capacity_soc=100i,battery_voltage=13.6,",
+				`,total_operating_days=0i,`,
+				`,total_charging_amp_hours=1234i,total_discharging_amp_hours=0i,cumulative_power_generation=0,`,
+				`,street_light_status=false,`,
+				`,charging_state="mppt charging mode",controller_faults="battery over voltage,load short circuit" 1691625600000000123`,
+			},
+		},
+		{
+			Name:        "partial reading with escaping",
+			Measurement: "solar charge",
+			Tags:        map[string]string{"site": "cabin, north", "empty": ""},
+			DCI: func() *gorenogymodbus.DynamicControllerInformation {
+				dci, _ := gorenogymodbus.ParseRange(0x100, []byte{0x00, 0x50, 0x00, 0x84})
+				return dci
+			},
+			Line: `solar\ charge,site=cabin\,\ north battery_capacity_soc=80i,battery_voltage=13.2 1691625600000000123`,
+		},
+		{
+			Name:        "no fields",
+			Measurement: "renogy",
+			DCI: func() *gorenogymodbus.DynamicControllerInformation {
+				dci, _ := gorenogymodbus.ParseRange(0x10A, []byte{0x00, 0x00})
+				return dci
+			},
+			ShouldError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			result, err := influx.Encode(tc.Measurement, tc.Tags, at, tc.DCI())
+			if tc.ShouldError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if tc.Line != "" {
+				assert.Equal(t, tc.Line, string(result))
+			}
+			for _, c := range tc.Contains {
+				assert.Contains(t, string(result), c)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package influx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+)
+
+type WriterConfig struct {
+	URL         string            // base url of the server, e.g. http://localhost:8086
+	Org         string            // InfluxDB 2 organization
+	Bucket      string            // InfluxDB 2 bucket
+	Token       string            // InfluxDB 2 api token
+	Measurement string            // defaults to "renogy"
+	Tags        map[string]string // device tags added to every reading, e.g. serial and model
+
+	BatchSize     int           // lines per write, defaults to 100
+	FlushInterval time.Duration // maximum age of a buffered line before it is written, defaults to 10 seconds
+	MaxBuffered   int           // lines kept while the server is unreachable, defaults to 10 × BatchSize
+
+	HTTPClient *http.Client // defaults to a client with a 10 second timeout
+}
+
+// Writer buffers readings and writes them to the InfluxDB 2 write api in batches.
+type Writer struct {
+	config WriterConfig
+	url    string
+
+	mu     sync.Mutex
+	lines  [][]byte
+	oldest time.Time
+}
+
+func NewWriter(config WriterConfig) (*Writer, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	u = u.JoinPath("api", "v2", "write")
+	u.RawQuery = url.Values{
+		"org":       {config.Org},
+		"bucket":    {config.Bucket},
+		"precision": {"ns"},
+	}.Encode()
+
+	if config.Measurement == "" {
+		config.Measurement = "renogy"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 10 * time.Second
+	}
+	if config.MaxBuffered < config.BatchSize {
+		config.MaxBuffered = 10 * config.BatchSize
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Writer{
+		config: config,
+		url:    u.String(),
+	}, nil
+}
+
+// Write encodes a reading taken at t and buffers it. The buffer is flushed when it
+// holds a full batch or its oldest line is older than the flush interval. Run
+// flushes it when no more readings arrive.
+func (w *Writer) Write(ctx context.Context, t time.Time, dci *gorenogymodbus.DynamicControllerInformation) error {
+	line, err := Encode(w.config.Measurement, w.config.Tags, t, dci)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.lines) == 0 {
+		w.oldest = time.Now()
+	}
+	w.lines = append(w.lines, line)
+	if len(w.lines) > w.config.MaxBuffered {
+		w.lines = w.lines[len(w.lines)-w.config.MaxBuffered:]
+	}
+
+	if len(w.lines) < w.config.BatchSize && time.Since(w.oldest) < w.config.FlushInterval {
+		return nil
+	}
+
+	return w.flush(ctx)
+}
+
+// Run flushes lines older than the flush interval until ctx is done and returns
+// ctx.Err(). Lines of a failed flush are kept and tried again. Call Flush after Run
+// returns to write the lines left.
+func (w *Writer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.config.FlushInterval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		w.mu.Lock()
+		if len(w.lines) > 0 && time.Since(w.oldest) >= w.config.FlushInterval {
+			w.flush(ctx) //nolint:errcheck // kept for the next attempt
+		}
+		w.mu.Unlock()
+	}
+}
+
+// Flush writes all buffered lines. Lines are kept for the next attempt if the
+// write fails.
+func (w *Writer) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.flush(ctx)
+}
+
+func (w *Writer) flush(ctx context.Context) error {
+	for len(w.lines) > 0 {
+		n := w.config.BatchSize
+		if n > len(w.lines) {
+			n = len(w.lines)
+		}
+
+		err := w.post(ctx, bytes.Join(w.lines[:n], []byte("\n")))
+		if err != nil {
+			return err
+		}
+
+		w.lines = w.lines[n:]
+		w.oldest = time.Now()
+	}
+
+	return nil
+}
+
+func (w *Writer) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.config.Token != "" {
+		req.Header.Set("Authorization", "Token "+w.config.Token)
+	}
+
+	res, err := w.config.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to write lines: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("failed to write lines: %s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
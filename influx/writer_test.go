@@ -0,0 +1,114 @@
+package influx_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/influx"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeServer struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (fs *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	b, _ := io.ReadAll(r.Body)
+	fs.requests = append(fs.requests, r)
+	fs.bodies = append(fs.bodies, string(b))
+	w.WriteHeader(fs.status)
+}
+
+func TestWriter(t *testing.T) {
+	fs := &fakeServer{status: http.StatusNoContent}
+	server := httptest.NewServer(fs)
+	defer server.Close()
+
+	w, err := influx.NewWriter(influx.WriterConfig{
+		URL:           server.URL,
+		Org:           "home",
+		Bucket:        "solar",
+		Token:         "secret",
+		Tags:          map[string]string{"serial": "1234"},
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	at := time.Unix(1691625600, 0)
+	dci := &gorenogymodbus.DynamicControllerInformation{BatteryCapacitySOC: 90}
+
+	assert.NoError(t, w.Write(ctx, at, dci))
+	assert.Empty(t, fs.bodies, "a partial batch should be buffered")
+
+	assert.NoError(t, w.Write(ctx, at.Add(time.Second), dci))
+	if assert.Len(t, fs.bodies, 1) {
+		r := fs.requests[0]
+		assert.Equal(t, "/api/v2/write", r.URL.Path)
+		assert.Equal(t, "home", r.URL.Query().Get("org"))
+		assert.Equal(t, "solar", r.URL.Query().Get("bucket"))
+		assert.Equal(t, "ns", r.URL.Query().Get("precision"))
+		assert.Equal(t, "Token secret", r.Header.Get("Authorization"))
+
+		lines := strings.Split(fs.bodies[0], "\n")
+		assert.Len(t, lines, 2)
+		assert.True(t, strings.HasPrefix(lines[0], "renogy,serial=1234 battery_capacity_soc=90i,"))
+		assert.True(t, strings.HasSuffix(lines[1], " 1691625601000000000"))
+	}
+
+	// failed writes are retried on the next flush
+	fs.status = http.StatusServiceUnavailable
+	assert.NoError(t, w.Write(ctx, at.Add(2*time.Second), dci))
+	assert.Error(t, w.Flush(ctx))
+
+	fs.status = http.StatusNoContent
+	assert.NoError(t, w.Flush(ctx))
+	assert.Len(t, fs.bodies, 3)
+	assert.Equal(t, fs.bodies[1], fs.bodies[2])
+}
+
+func TestWriterRun(t *testing.T) {
+	fs := &fakeServer{status: http.StatusNoContent}
+	server := httptest.NewServer(fs)
+	defer server.Close()
+
+	w, err := influx.NewWriter(influx.WriterConfig{
+		URL:           server.URL,
+		BatchSize:     10,
+		FlushInterval: 20 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	dci := &gorenogymodbus.DynamicControllerInformation{BatteryCapacitySOC: 90}
+	assert.NoError(t, w.Write(context.Background(), time.Unix(1691625600, 0), dci))
+
+	// a quiet stream is flushed once its line is older than the flush interval
+	assert.Eventually(t, func() bool {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		return len(fs.bodies) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
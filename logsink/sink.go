@@ -0,0 +1,439 @@
+// Package logsink appends timestamped readings to CSV or JSON Lines files, rotating
+// them daily or by size and compressing rotated files with gzip.
+package logsink
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+)
+
+type Format int
+
+const (
+	CSV Format = iota
+	JSONLines
+)
+
+func (f Format) String() string {
+	switch f {
+	case CSV:
+		return "csv"
+	case JSONLines:
+		return "jsonl"
+	default:
+		return "unknown"
+	}
+}
+
+type Config struct {
+	Dir      string // directory of the log files
+	Prefix   string // file name prefix, defaults to "renogy"
+	Format   Format
+	Daily    bool  // start a new file when the date of a reading changes
+	MaxBytes int64 // start a new file rather than take the current one, header included, over this size; 0 to disable
+	Compress bool  // gzip rotated files
+}
+
+// Sink appends readings to <prefix>-<date>[.<n>].<csv|jsonl> in Dir. It is not safe
+// for concurrent use.
+type Sink struct {
+	config Config
+	header []byte // written to empty CSV files
+
+	file  *os.File
+	w     *bufio.Writer
+	name  string
+	day   string
+	index int
+	size  int64
+}
+
+func New(config Config) (*Sink, error) {
+	if config.Prefix == "" {
+		config.Prefix = "renogy"
+	}
+	if config.Format != CSV && config.Format != JSONLines {
+		return nil, fmt.Errorf("invalid format: %d", config.Format)
+	}
+
+	var header []byte
+	if config.Format == CSV {
+		var err error
+		header, err = csvLine(Columns())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.MaxBytes > 0 && config.MaxBytes <= int64(len(header)) {
+		return nil, fmt.Errorf("max bytes leaves no room after the csv header: %d", config.MaxBytes)
+	}
+
+	err := os.MkdirAll(config.Dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	return &Sink{config: config, header: header}, nil
+}
+
+// Columns returns the CSV columns: the timestamp followed by the json names of
+// the DynamicControllerInformation fields, in struct order.
+func Columns() []string {
+	columns := []string{"timestamp"}
+	for _, f := range (&gorenogymodbus.DynamicControllerInformation{}).Fields() {
+		columns = append(columns, f.Name)
+	}
+	return columns
+}
+
+// Write appends a reading taken at t, rotating the file first if needed.
+func (s *Sink) Write(t time.Time, dci *gorenogymodbus.DynamicControllerInformation) error {
+	var record []byte
+	var err error
+	switch s.config.Format {
+	case CSV:
+		record, err = csvRecord(t, dci)
+	case JSONLines:
+		record, err = jsonRecord(t, dci)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.rotate(t, int64(len(record)))
+	if err != nil {
+		return err
+	}
+
+	if s.size == 0 && len(s.header) > 0 {
+		err = s.write(s.header)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.write(record)
+	if err != nil {
+		return err
+	}
+
+	return s.w.Flush()
+}
+
+// Close closes the current file. It is not compressed, so a later Sink can keep
+// appending to it.
+func (s *Sink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.w.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to flush log file: %w", err)
+	}
+
+	err = s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *Sink) write(b []byte) error {
+	n, err := s.w.Write(b)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write log file: %w", err)
+	}
+	return nil
+}
+
+// dayLayout formats the day in the names of daily files.
+const dayLayout = "2006-01-02"
+
+func (s *Sink) rotate(t time.Time, next int64) error {
+	day := t.Format(dayLayout)
+	if !s.config.Daily {
+		day = ""
+	}
+
+	switch {
+	case s.file == nil:
+		err := s.open(day, s.lastIndex(day), next)
+		if err != nil {
+			return err
+		}
+		return s.compressLeftovers()
+	case day != s.day:
+		err := s.closeAndCompress()
+		if err != nil {
+			return err
+		}
+		return s.open(day, 0, next)
+	case !s.fits(s.size, next):
+		err := s.closeAndCompress()
+		if err != nil {
+			return err
+		}
+		return s.open(day, s.index+1, next)
+	}
+
+	return nil
+}
+
+func (s *Sink) open(day string, index int, next int64) error {
+	for {
+		name := s.fileName(day, index)
+
+		info, err := os.Stat(name)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return fmt.Errorf("failed to stat log file: %w", err)
+		case !s.fits(info.Size(), next):
+			index++
+			continue
+		}
+
+		if _, err := os.Stat(name + ".gz"); err == nil {
+			index++
+			continue
+		}
+
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+
+		info, err = f.Stat()
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("failed to stat log file: %w", err)
+		}
+
+		s.file = f
+		s.w = bufio.NewWriter(f)
+		s.name = name
+		s.day = day
+		s.index = index
+		s.size = info.Size()
+		return nil
+	}
+}
+
+// fits reports whether a record of next bytes can be appended to a file of size
+// bytes, which includes its CSV header, without taking it over MaxBytes. Empty
+// files take any record so records larger than MaxBytes are still written.
+func (s *Sink) fits(size int64, next int64) bool {
+	if s.config.MaxBytes <= 0 || size == 0 {
+		return true
+	}
+	return size+next <= s.config.MaxBytes
+}
+
+// compressLeftovers compresses the files of the prefix left uncompressed by an
+// earlier sink, such as the previous day's file when restarting on a new day.
+func (s *Sink) compressLeftovers() error {
+	if !s.config.Compress {
+		return nil
+	}
+
+	ext := "." + s.config.Format.String()
+	matches, err := filepath.Glob(filepath.Join(s.config.Dir, s.config.Prefix+"*"+ext))
+	if err != nil {
+		return fmt.Errorf("failed to find log files: %w", err)
+	}
+
+	for _, m := range matches {
+		if m == s.name || !s.ownsFile(filepath.Base(m)) {
+			continue
+		}
+		if _, err := os.Stat(m + ".gz"); err == nil {
+			continue
+		}
+
+		err = compress(m)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ownsFile reports whether name is a file name the sink writes, the prefix followed
+// by an optional day and index, so files of another prefix starting with this one
+// are left alone.
+func (s *Sink) ownsFile(name string) bool {
+	rest := strings.TrimSuffix(strings.TrimPrefix(name, s.config.Prefix), "."+s.config.Format.String())
+
+	if strings.HasPrefix(rest, "-") {
+		if len(rest) < len(dayLayout)+1 {
+			return false
+		}
+		if _, err := time.Parse(dayLayout, rest[1:len(dayLayout)+1]); err != nil {
+			return false
+		}
+		rest = rest[len(dayLayout)+1:]
+	}
+	if rest == "" {
+		return true
+	}
+
+	n, err := strconv.Atoi(strings.TrimPrefix(rest, "."))
+	return rest[0] == '.' && err == nil && n > 0
+}
+
+// lastIndex finds the highest index used for day, so a restarted sink appends to
+// the most recent file rather than the first one.
+func (s *Sink) lastIndex(day string) int {
+	matches, err := filepath.Glob(filepath.Join(s.config.Dir, s.baseName(day)+".*"))
+	if err != nil {
+		return 0
+	}
+
+	var indexes []int
+	for _, m := range matches {
+		parts := strings.Split(strings.TrimPrefix(filepath.Base(m), s.baseName(day)+"."), ".")
+		if n, err := strconv.Atoi(parts[0]); err == nil {
+			indexes = append(indexes, n)
+		}
+	}
+	if len(indexes) == 0 {
+		return 0
+	}
+
+	sort.Ints(indexes)
+	return indexes[len(indexes)-1]
+}
+
+func (s *Sink) baseName(day string) string {
+	if day == "" {
+		return s.config.Prefix
+	}
+	return s.config.Prefix + "-" + day
+}
+
+func (s *Sink) fileName(day string, index int) string {
+	name := s.baseName(day)
+	if index > 0 {
+		name += "." + strconv.Itoa(index)
+	}
+	return filepath.Join(s.config.Dir, name+"."+s.config.Format.String())
+}
+
+func (s *Sink) closeAndCompress() error {
+	name := s.name
+
+	err := s.Close()
+	if err != nil {
+		return err
+	}
+
+	if !s.config.Compress {
+		return nil
+	}
+
+	return compress(name)
+}
+
+func compress(name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open rotated log file: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create compressed log file: %w", err)
+	}
+	defer out.Close()
+
+	gw := gzip.NewWriter(out)
+	gw.Name = filepath.Base(name)
+
+	_, err = io.Copy(gw, in)
+	if err != nil {
+		return fmt.Errorf("failed to compress log file: %w", err)
+	}
+
+	err = gw.Close()
+	if err != nil {
+		return fmt.Errorf("failed to compress log file: %w", err)
+	}
+
+	err = out.Close()
+	if err != nil {
+		return fmt.Errorf("failed to compress log file: %w", err)
+	}
+
+	return os.Remove(name)
+}
+
+func csvRecord(t time.Time, dci *gorenogymodbus.DynamicControllerInformation) ([]byte, error) {
+	values := make(map[string]interface{})
+	for _, f := range dci.Fields() {
+		values[f.Name] = f.Value
+	}
+
+	columns := Columns()
+	record := make([]string, len(columns))
+	record[0] = t.Format(time.RFC3339Nano)
+	for i, name := range columns[1:] {
+		if v, ok := values[name]; ok {
+			record[i+1] = csvValue(v)
+		}
+	}
+
+	return csvLine(record)
+}
+
+func csvLine(record []string) ([]byte, error) {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+
+	err := w.Write(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode csv record: %w", err)
+	}
+	w.Flush()
+
+	return []byte(b.String()), w.Error()
+}
+
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case decimal.Decimal:
+		return v.String()
+	case []string:
+		return strings.Join(v, ";")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func jsonRecord(t time.Time, dci *gorenogymodbus.DynamicControllerInformation) ([]byte, error) {
+	fields := map[string]interface{}{"timestamp": t}
+	for _, f := range dci.Fields() {
+		fields[f.Name] = f.Value
+	}
+
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal reading: %w", err)
+	}
+
+	return append(b, '\n'), nil
+}
@@ -0,0 +1,179 @@
+package logsink_test
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/logsink"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listDir(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestColumns(t *testing.T) {
+	columns := logsink.Columns()
+	assert.Equal(t, "timestamp", columns[0])
+	assert.Equal(t, "battery_capacity_soc", columns[1])
+	assert.Equal(t, "controller_faults", columns[len(columns)-1])
+	assert.Len(t, columns, 33)
+}
+
+func TestCSVDailyRotation(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2023, 8, 10, 23, 0, 0, 0, time.UTC)
+	dci := &gorenogymodbus.DynamicControllerInformation{
+		BatteryCapacitySOC: 80,
+		BatteryVoltage:     decimal.NewFromFloat(13.2),
+		ControllerFaults:   []string{"load short circuit", "battery over voltage"},
+	}
+
+	s, err := logsink.New(logsink.Config{Dir: dir, Format: logsink.CSV, Daily: true, Compress: true})
+	require.NoError(t, err)
+	require.NoError(t, s.Write(start, dci))
+	require.NoError(t, s.Write(start.Add(30*time.Minute), dci))
+	require.NoError(t, s.Write(start.Add(2*time.Hour), dci))
+	require.NoError(t, s.Close())
+
+	assert.Equal(t, []string{"renogy-2023-08-10.csv.gz", "renogy-2023-08-11.csv"}, listDir(t, dir))
+
+	f, err := os.Open(filepath.Join(dir, "renogy-2023-08-10.csv.gz"))
+	require.NoError(t, err)
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(gr).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, logsink.Columns(), records[0])
+	assert.Equal(t, "2023-08-10T23:00:00Z", records[1][0])
+	assert.Equal(t, "80", records[1][1])
+	assert.Equal(t, "13.2", records[1][2])
+	assert.Equal(t, "load short circuit;battery over voltage", records[1][32])
+
+	// a restarted sink appends without repeating the header
+	s, err = logsink.New(logsink.Config{Dir: dir, Format: logsink.CSV, Daily: true, Compress: true})
+	require.NoError(t, err)
+	require.NoError(t, s.Write(start.Add(3*time.Hour), dci))
+	require.NoError(t, s.Close())
+
+	b, err := os.ReadFile(filepath.Join(dir, "renogy-2023-08-11.csv"))
+	require.NoError(t, err)
+	records, err = csv.NewReader(strings.NewReader(string(b))).ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+}
+
+func TestJSONLinesSizeRotation(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	dci, err := gorenogymodbus.ParseRange(0x100, []byte{0x00, 0x50, 0x00, 0x84})
+	require.NoError(t, err)
+
+	s, err := logsink.New(logsink.Config{Dir: dir, Prefix: "cabin", Format: logsink.JSONLines, MaxBytes: 200})
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.Write(start.Add(time.Duration(i)*time.Second), dci))
+	}
+	require.NoError(t, s.Close())
+
+	names := listDir(t, dir)
+	assert.Equal(t, []string{"cabin.1.jsonl", "cabin.2.jsonl", "cabin.jsonl"}, names)
+
+	f, err := os.Open(filepath.Join(dir, "cabin.jsonl"))
+	require.NoError(t, err)
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Len(t, lines, 2)
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, map[string]interface{}{
+		"timestamp":            "2023-08-10T12:00:00Z",
+		"battery_capacity_soc": 80.0,
+		"battery_voltage":      "13.2",
+	}, record)
+}
+
+func TestCSVSizeRotation(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	dci := &gorenogymodbus.DynamicControllerInformation{BatteryCapacitySOC: 80}
+
+	_, err := logsink.New(logsink.Config{Dir: dir, Format: logsink.CSV, MaxBytes: 100})
+	assert.Error(t, err, "max bytes smaller than the header")
+
+	s, err := logsink.New(logsink.Config{Dir: dir, Format: logsink.CSV, MaxBytes: 1000})
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.Write(start.Add(time.Duration(i)*time.Second), dci))
+	}
+	require.NoError(t, s.Close())
+
+	names := listDir(t, dir)
+	assert.Greater(t, len(names), 1)
+	rows := 0
+	for _, name := range names {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(b), 1000, name)
+
+		records, err := csv.NewReader(strings.NewReader(string(b))).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, logsink.Columns(), records[0], name)
+		rows += len(records) - 1
+	}
+	assert.Equal(t, 5, rows)
+}
+
+func TestCompressLeftoverAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2023, 8, 10, 23, 0, 0, 0, time.UTC)
+	dci := &gorenogymodbus.DynamicControllerInformation{BatteryCapacitySOC: 80}
+	config := logsink.Config{Dir: dir, Format: logsink.JSONLines, Daily: true, Compress: true}
+
+	s, err := logsink.New(config)
+	require.NoError(t, err)
+	require.NoError(t, s.Write(start, dci))
+	require.NoError(t, s.Close())
+	assert.Equal(t, []string{"renogy-2023-08-10.jsonl"}, listDir(t, dir))
+
+	// a sink of another prefix writes to the same directory
+	shed := config
+	shed.Prefix = "renogy-shed"
+	s, err = logsink.New(shed)
+	require.NoError(t, err)
+	require.NoError(t, s.Write(start.Add(2*time.Hour), dci))
+
+	// restarted on the next day
+	restarted, err := logsink.New(config)
+	require.NoError(t, err)
+	require.NoError(t, restarted.Write(start.Add(2*time.Hour), dci))
+	require.NoError(t, restarted.Close())
+	require.NoError(t, s.Close())
+	assert.Equal(t, []string{"renogy-2023-08-10.jsonl.gz", "renogy-2023-08-11.jsonl", "renogy-shed-2023-08-11.jsonl"}, listDir(t, dir))
+}
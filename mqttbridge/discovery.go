@@ -0,0 +1,71 @@
+package mqttbridge
+
+// sensor describes how a DynamicControllerInformation field appears in Home Assistant.
+type sensor struct {
+	field       string // json name of the field
+	name        string
+	deviceClass string
+	unit        string
+	stateClass  string
+	template    string // value template, defaults to the field value
+}
+
+var sensors = []sensor{
+	{field: "battery_capacity_soc", name: "Battery SOC", deviceClass: "battery", unit: "%", stateClass: "measurement"},
+	{field: "battery_voltage", name: "Battery Voltage", deviceClass: "voltage", unit: "V", stateClass: "measurement"},
+	{field: "charging_current", name: "Charging Current", deviceClass: "current", unit: "A", stateClass: "measurement"},
+	{field: "controller_temperature", name: "Controller Temperature", deviceClass: "temperature", unit: "°C", stateClass: "measurement"},
+	{field: "battery_temperature", name: "Battery Temperature", deviceClass: "temperature", unit: "°C", stateClass: "measurement"},
+	{field: "street_light_load_voltage", name: "Load Voltage", deviceClass: "voltage", unit: "V", stateClass: "measurement"},
+	{field: "street_light_load_current", name: "Load Current", deviceClass: "current", unit: "A", stateClass: "measurement"},
+	{field: "street_light_load_power", name: "Load Power", deviceClass: "power", unit: "W", stateClass: "measurement"},
+	{field: "solar_panel_voltage", name: "Solar Panel Voltage", deviceClass: "voltage", unit: "V", stateClass: "measurement"},
+	{field: "solar_panel_current", name: "Solar Panel Current", deviceClass: "current", unit: "A", stateClass: "measurement"},
+	{field: "charging_power", name: "Charging Power", deviceClass: "power", unit: "W", stateClass: "measurement"},
+	{field: "battery_minimum_voltage_current_day", name: "Battery Minimum Voltage Today", deviceClass: "voltage", unit: "V", stateClass: "measurement"},
+	{field: "battery_maximum_voltage_current_day", name: "Battery Maximum Voltage Today", deviceClass: "voltage", unit: "V", stateClass: "measurement"},
+	{field: "maximum_charging_current_current_day", name: "Maximum Charging Current Today", deviceClass: "current", unit: "A", stateClass: "measurement"},
+	{field: "maximum_discharging_current_current_day", name: "Maximum Discharging Current Today", deviceClass: "current", unit: "A", stateClass: "measurement"},
+	{field: "maximum_charging_power_current_day", name: "Maximum Charging Power Today", deviceClass: "power", unit: "W", stateClass: "measurement"},
+	{field: "maximum_discharging_power_current_day", name: "Maximum Discharging Power Today", deviceClass: "power", unit: "W", stateClass: "measurement"},
+	{field: "charging_amp_hours_current_day", name: "Charging Amp Hours Today", unit: "Ah", stateClass: "total_increasing"},
+	{field: "discharging_amp_hours_current_day", name: "Discharging Amp Hours Today", unit: "Ah", stateClass: "total_increasing"},
+	{field: "power_generation_current_day", name: "Power Generation Today", deviceClass: "energy", unit: "kWh", stateClass: "total_increasing"},
+	{field: "power_consumption_current_day", name: "Power Consumption Today", deviceClass: "energy", unit: "kWh", stateClass: "total_increasing"},
+	{field: "total_operating_days", name: "Operating Days", deviceClass: "duration", unit: "d", stateClass: "total_increasing"},
+	{field: "total_battery_over_discharges", name: "Battery Over Discharges", stateClass: "total_increasing"},
+	{field: "total_battery_full_charges", name: "Battery Full Charges", stateClass: "total_increasing"},
+	{field: "total_charging_amp_hours", name: "Total Charging Amp Hours", unit: "Ah", stateClass: "total_increasing"},
+	{field: "total_discharging_amp_hours", name: "Total Discharging Amp Hours", unit: "Ah", stateClass: "total_increasing"},
+	{field: "cumulative_power_generation", name: "Cumulative Power Generation", deviceClass: "energy", unit: "kWh", stateClass: "total_increasing"},
+	{field: "cumulative_power_consumption", name: "Cumulative Power Consumption", deviceClass: "energy", unit: "kWh", stateClass: "total_increasing"},
+	{field: "street_light_brightness", name: "Load Brightness", unit: "%", stateClass: "measurement"},
+	{field: "charging_state", name: "Charging State"},
+	{field: "controller_faults", name: "Controller Faults", template: "{{ value_json.controller_faults | join(', ') if value_json.controller_faults else 'none' }}"},
+}
+
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name,omitempty"`
+	Model        string   `json:"model,omitempty"`
+	Manufacturer string   `json:"manufacturer,omitempty"`
+}
+
+type discoveryConfig struct {
+	Name                string          `json:"name"`
+	UniqueID            string          `json:"unique_id"`
+	StateTopic          string          `json:"state_topic"`
+	ValueTemplate       string          `json:"value_template,omitempty"`
+	DeviceClass         string          `json:"device_class,omitempty"`
+	UnitOfMeasurement   string          `json:"unit_of_measurement,omitempty"`
+	StateClass          string          `json:"state_class,omitempty"`
+	CommandTopic        string          `json:"command_topic,omitempty"`
+	PayloadOn           string          `json:"payload_on,omitempty"`
+	PayloadOff          string          `json:"payload_off,omitempty"`
+	StateOn             string          `json:"state_on,omitempty"`
+	StateOff            string          `json:"state_off,omitempty"`
+	AvailabilityTopic   string          `json:"availability_topic"`
+	PayloadAvailable    string          `json:"payload_available"`
+	PayloadNotAvailable string          `json:"payload_not_available"`
+	Device              discoveryDevice `json:"device"`
+}
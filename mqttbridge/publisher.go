@@ -0,0 +1,247 @@
+// Package mqttbridge publishes controller readings to MQTT, announces them to
+// Home Assistant with MQTT discovery and switches the load from a command topic.
+package mqttbridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	payloadOnline  = "online"
+	payloadOffline = "offline"
+	payloadOn      = "ON"
+	payloadOff     = "OFF"
+)
+
+type Config struct {
+	DeviceID        string // unique id of the controller, e.g. its serial number
+	DeviceName      string // defaults to "Renogy <DeviceID>"
+	Model           string
+	TopicPrefix     string // defaults to "renogy/<DeviceID>"
+	DiscoveryPrefix string // defaults to "homeassistant"
+	QoS             byte
+	Timeout         time.Duration // defaults to 10 seconds
+}
+
+// LoadSwitch switches the load on or off, *gorenogymodbus.ModbusClient implements it.
+type LoadSwitch interface {
+	SetLoad(on bool) error
+}
+
+// Publisher publishes readings under <prefix>/<field> and <prefix>/state, and
+// availability under <prefix>/availability with a last will of "offline".
+// Messages of "ON" or "OFF" on <prefix>/load/set switch the load.
+type Publisher struct {
+	client mqtt.Client
+	config Config
+	load   LoadSwitch
+	errors chan error
+}
+
+// NewPublisher creates a publisher connecting with opts. load may be nil, in which
+// case no load switch is announced or subscribed to. Errors from handling load
+// commands are delivered on Errors.
+func NewPublisher(opts *mqtt.ClientOptions, config Config, load LoadSwitch) (*Publisher, error) {
+	if config.DeviceID == "" {
+		return nil, fmt.Errorf("device id is required")
+	}
+	if config.DeviceName == "" {
+		config.DeviceName = "Renogy " + config.DeviceID
+	}
+	if config.TopicPrefix == "" {
+		config.TopicPrefix = "renogy/" + config.DeviceID
+	}
+	if config.DiscoveryPrefix == "" {
+		config.DiscoveryPrefix = "homeassistant"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	p := &Publisher{
+		config: config,
+		load:   load,
+		errors: make(chan error, 16),
+	}
+
+	opts.SetWill(p.topic("availability"), payloadOffline, config.QoS, true)
+	opts.SetOnConnectHandler(func(c mqtt.Client) {
+		err := p.announce()
+		if err != nil {
+			p.reportError(err)
+		}
+	})
+	p.client = mqtt.NewClient(opts)
+
+	return p, nil
+}
+
+// Connect connects to the broker. Discovery configs and availability are published
+// and the command topic is subscribed on every (re)connect.
+func (p *Publisher) Connect() error {
+	return p.wait(p.client.Connect(), "failed to connect to broker")
+}
+
+// Close publishes "offline" and disconnects.
+func (p *Publisher) Close() error {
+	err := p.wait(p.client.Publish(p.topic("availability"), p.config.QoS, true, payloadOffline), "failed to publish availability")
+	p.client.Disconnect(uint(p.config.Timeout / time.Millisecond))
+	return err
+}
+
+// Errors returns errors from handling load commands.
+func (p *Publisher) Errors() <-chan error {
+	return p.errors
+}
+
+// Publish publishes a reading as json to <prefix>/state and each field to
+// <prefix>/<field>. Fields absent from a partial reading are left out of both.
+func (p *Publisher) Publish(dci *gorenogymodbus.DynamicControllerInformation) error {
+	fields := dci.Fields()
+	values := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		values[f.Name] = f.Value
+	}
+	state, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reading: %w", err)
+	}
+
+	err = p.wait(p.client.Publish(p.topic("state"), p.config.QoS, true, state), "failed to publish state")
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		err = p.wait(p.client.Publish(p.topic(f.Name), p.config.QoS, true, fieldPayload(f.Value)), "failed to publish "+f.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Publisher) announce() error {
+	device := discoveryDevice{
+		Identifiers:  []string{p.config.DeviceID},
+		Name:         p.config.DeviceName,
+		Model:        p.config.Model,
+		Manufacturer: "Renogy",
+	}
+
+	for _, s := range sensors {
+		template := s.template
+		if template == "" {
+			template = fmt.Sprintf("{{ value_json.%s }}", s.field)
+		}
+
+		err := p.publishDiscovery("sensor", s.field, discoveryConfig{
+			Name:              s.name,
+			StateTopic:        p.topic("state"),
+			ValueTemplate:     template,
+			DeviceClass:       s.deviceClass,
+			UnitOfMeasurement: s.unit,
+			StateClass:        s.stateClass,
+			Device:            device,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.load != nil {
+		err := p.publishDiscovery("switch", "load", discoveryConfig{
+			Name:         "Load",
+			StateTopic:   p.topic("street_light_status"),
+			CommandTopic: p.topic("load/set"),
+			PayloadOn:    payloadOn,
+			PayloadOff:   payloadOff,
+			StateOn:      "true",
+			StateOff:     "false",
+			Device:       device,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = p.wait(p.client.Subscribe(p.topic("load/set"), p.config.QoS, p.handleLoadCommand), "failed to subscribe to load command topic")
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.wait(p.client.Publish(p.topic("availability"), p.config.QoS, true, payloadOnline), "failed to publish availability")
+}
+
+func (p *Publisher) publishDiscovery(component string, objectID string, config discoveryConfig) error {
+	config.UniqueID = p.config.DeviceID + "_" + objectID
+	config.AvailabilityTopic = p.topic("availability")
+	config.PayloadAvailable = payloadOnline
+	config.PayloadNotAvailable = payloadOffline
+
+	b, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal discovery config: %w", err)
+	}
+
+	topic := strings.Join([]string{p.config.DiscoveryPrefix, component, p.config.DeviceID, objectID, "config"}, "/")
+	return p.wait(p.client.Publish(topic, p.config.QoS, true, b), "failed to publish discovery config")
+}
+
+func (p *Publisher) handleLoadCommand(c mqtt.Client, m mqtt.Message) {
+	var on bool
+	switch strings.ToUpper(strings.TrimSpace(string(m.Payload()))) {
+	case payloadOn:
+		on = true
+	case payloadOff:
+		on = false
+	default:
+		p.reportError(fmt.Errorf("invalid load command: %q", m.Payload()))
+		return
+	}
+
+	err := p.load.SetLoad(on)
+	if err != nil {
+		p.reportError(err)
+	}
+}
+
+func (p *Publisher) reportError(err error) {
+	select {
+	case p.errors <- err:
+	default:
+	}
+}
+
+func (p *Publisher) topic(name string) string {
+	return p.config.TopicPrefix + "/" + name
+}
+
+func (p *Publisher) wait(t mqtt.Token, msg string) error {
+	if !t.WaitTimeout(p.config.Timeout) {
+		return fmt.Errorf("%s: timed out", msg)
+	}
+	if t.Error() != nil {
+		return fmt.Errorf("%s: %w", msg, t.Error())
+	}
+	return nil
+}
+
+func fieldPayload(v interface{}) string {
+	switch v := v.(type) {
+	case decimal.Decimal:
+		return v.String()
+	case []string:
+		return strings.Join(v, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,156 @@
+package mqttbridge_test
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/mqttbridge"
+	server "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLoad struct {
+	calls chan bool
+}
+
+func (fl *fakeLoad) SetLoad(on bool) error {
+	fl.calls <- on
+	return nil
+}
+
+type recorder struct {
+	mu       sync.Mutex
+	messages map[string]string
+}
+
+func (r *recorder) handle(c mqtt.Client, m mqtt.Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.messages[m.Topic()] = string(m.Payload())
+}
+
+func (r *recorder) get(topic string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.messages[topic]
+	return m, ok
+}
+
+func startBroker(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	s := server.New(nil)
+	require.NoError(t, s.AddHook(new(auth.AllowHook), nil))
+	require.NoError(t, s.AddListener(listeners.NewTCP("t1", address, nil)))
+	go func() {
+		_ = s.Serve()
+	}()
+	t.Cleanup(func() {
+		s.Close()
+	})
+
+	return "tcp://" + address
+}
+
+func TestPublisher(t *testing.T) {
+	broker := startBroker(t)
+
+	rec := &recorder{messages: map[string]string{}}
+	observer := mqtt.NewClient(mqtt.NewClientOptions().AddBroker(broker).SetClientID("observer"))
+	require.True(t, observer.Connect().WaitTimeout(5*time.Second))
+	require.True(t, observer.Subscribe("#", 1, rec.handle).WaitTimeout(5*time.Second))
+	defer observer.Disconnect(100)
+
+	load := &fakeLoad{calls: make(chan bool, 1)}
+	p, err := mqttbridge.NewPublisher(mqtt.NewClientOptions().AddBroker(broker).SetClientID("publisher"), mqttbridge.Config{
+		DeviceID: "1234",
+		Model:    "RNG-CTRL-RVR40",
+		QoS:      1,
+	}, load)
+	require.NoError(t, err)
+	require.NoError(t, p.Connect())
+
+	assert.Eventually(t, func() bool {
+		m, _ := rec.get("renogy/1234/availability")
+		return m == "online"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	config, ok := rec.get("homeassistant/sensor/1234/battery_voltage/config")
+	if assert.True(t, ok) {
+		var c map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(config), &c))
+		assert.Equal(t, "voltage", c["device_class"])
+		assert.Equal(t, "V", c["unit_of_measurement"])
+		assert.Equal(t, "measurement", c["state_class"])
+		assert.Equal(t, "1234_battery_voltage", c["unique_id"])
+		assert.Equal(t, "renogy/1234/state", c["state_topic"])
+		assert.Equal(t, "renogy/1234/availability", c["availability_topic"])
+	}
+	_, ok = rec.get("homeassistant/switch/1234/load/config")
+	assert.True(t, ok)
+
+	require.NoError(t, p.Publish(&gorenogymodbus.DynamicControllerInformation{
+		BatteryCapacitySOC: 80,
+		BatteryVoltage:     decimal.NewFromFloat(13.2),
+		StreetLightStatus:  true,
+		ControllerFaults:   []string{"load short circuit"},
+	}))
+
+	assert.Eventually(t, func() bool {
+		m, _ := rec.get("renogy/1234/controller_faults")
+		return m == "load short circuit"
+	}, 5*time.Second, 10*time.Millisecond)
+	m, _ := rec.get("renogy/1234/battery_voltage")
+	assert.Equal(t, "13.2", m)
+	m, _ = rec.get("renogy/1234/street_light_status")
+	assert.Equal(t, "true", m)
+	m, _ = rec.get("renogy/1234/state")
+	assert.Contains(t, m, `"battery_capacity_soc":80`)
+
+	// fields absent from a partial reading are not published as zero
+	partial, err := gorenogymodbus.ParseRange(0x100, []byte{0x00, 0x4b})
+	require.NoError(t, err)
+	require.NoError(t, p.Publish(partial))
+	assert.Eventually(t, func() bool {
+		m, _ := rec.get("renogy/1234/state")
+		return m == `{"battery_capacity_soc":75}`
+	}, 5*time.Second, 10*time.Millisecond)
+	m, _ = rec.get("renogy/1234/battery_voltage")
+	assert.Equal(t, "13.2", m)
+
+	require.True(t, observer.Publish("renogy/1234/load/set", 1, false, "OFF").WaitTimeout(5*time.Second))
+	select {
+	case on := <-load.calls:
+		assert.False(t, on)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "load command was not handled")
+	}
+
+	require.True(t, observer.Publish("renogy/1234/load/set", 1, false, "toggle").WaitTimeout(5*time.Second))
+	select {
+	case err := <-p.Errors():
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "invalid load command was not reported")
+	}
+
+	require.NoError(t, p.Close())
+	assert.Eventually(t, func() bool {
+		m, _ := rec.get("renogy/1234/availability")
+		return m == "offline"
+	}, 5*time.Second, 10*time.Millisecond)
+}
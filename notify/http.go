@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/michaelpeterswa/go-renogy-modbus/alert"
+)
+
+// Webhook posts notifications as JSON to a URL.
+type Webhook struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client // http.DefaultClient when nil
+}
+
+func (w *Webhook) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+
+	return send(w.Client, req)
+}
+
+// Ntfy publishes notifications to an ntfy topic URL, such as https://ntfy.sh/topic.
+type Ntfy struct {
+	URL    string
+	Token  string // access token, optional
+	Client *http.Client
+}
+
+var ntfyPriorities = map[alert.Severity]int{
+	alert.Info:     3,
+	alert.Warning:  4,
+	alert.Critical: 5,
+}
+
+func (nt *Ntfy) Notify(ctx context.Context, n Notification) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, nt.URL, bytes.NewBufferString(n.Message))
+	if err != nil {
+		return fmt.Errorf("failed to create ntfy request: %w", err)
+	}
+	req.Header.Set("Title", n.Title)
+	req.Header.Set("Priority", strconv.Itoa(ntfyPriorities[n.Severity]))
+	req.Header.Set("Tags", n.Severity.String()+","+n.Kind)
+	if nt.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+nt.Token)
+	}
+
+	return send(nt.Client, req)
+}
+
+// Gotify sends notifications to the message endpoint of a Gotify server with an
+// application token.
+type Gotify struct {
+	URL    string // server, such as https://gotify.example.com
+	Token  string
+	Client *http.Client
+}
+
+var gotifyPriorities = map[alert.Severity]int{
+	alert.Info:     2,
+	alert.Warning:  5,
+	alert.Critical: 8,
+}
+
+func (g *Gotify) Notify(ctx context.Context, n Notification) error {
+	u, err := url.JoinPath(g.URL, "message")
+	if err != nil {
+		return fmt.Errorf("failed to build gotify url: %w", err)
+	}
+
+	body, err := json.Marshal(struct {
+		Title    string `json:"title"`
+		Message  string `json:"message"`
+		Priority int    `json:"priority"`
+	}{n.Title, n.Message, gotifyPriorities[n.Severity]})
+	if err != nil {
+		return fmt.Errorf("failed to marshal gotify message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create gotify request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", g.Token)
+
+	return send(g.Client, req)
+}
+
+// send performs a request, failing on non 2xx responses. Client errors other than
+// 408 and 429 are permanent, retrying them won't help.
+func send(client *http.Client, req *http.Request) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		io.Copy(io.Discard, res.Body) //nolint:errcheck
+		return nil
+	}
+
+	detail, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	err = fmt.Errorf("unexpected response status: %s: %s", res.Status, bytes.TrimSpace(detail))
+	if res.StatusCode >= 400 && res.StatusCode < 500 &&
+		res.StatusCode != http.StatusRequestTimeout && res.StatusCode != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/michaelpeterswa/go-renogy-modbus/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// server records requests and answers them with status.
+func server(t *testing.T, status int) (*httptest.Server, *[]request) {
+	var requests []request
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests = append(requests, request{r.Method, r.URL.String(), r.Header, body})
+		w.WriteHeader(status)
+		io.WriteString(w, http.StatusText(status)) //nolint:errcheck
+	}))
+	t.Cleanup(s.Close)
+	return s, &requests
+}
+
+func rendered(t *testing.T) notify.Notification {
+	n := notification()
+	require.NoError(t, notify.DefaultTemplates().Render(&n))
+	return n
+}
+
+func TestWebhook(t *testing.T) {
+	s, requests := server(t, http.StatusNoContent)
+
+	w := &notify.Webhook{URL: s.URL + "/hook", Headers: map[string]string{"Authorization": "Bearer secret"}}
+	require.NoError(t, w.Notify(context.Background(), rendered(t)))
+
+	require.Len(t, *requests, 1)
+	r := (*requests)[0]
+	assert.Equal(t, http.MethodPost, r.Method)
+	assert.Equal(t, "/hook", r.Path)
+	assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+	assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal(r.Body, &payload))
+	assert.Equal(t, "alert", payload["kind"])
+	assert.Equal(t, "low_soc", payload["name"])
+	assert.Equal(t, "firing", payload["state"])
+	assert.Equal(t, "critical", payload["severity"])
+	assert.Equal(t, "[critical] low_soc firing", payload["title"])
+	assert.Equal(t, float64(18), payload["reading"].(map[string]interface{})["battery_capacity_soc"])
+}
+
+func TestHTTPErrors(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Status    int
+		Permanent bool
+	}{
+		{Name: "server error", Status: http.StatusBadGateway, Permanent: false},
+		{Name: "too many requests", Status: http.StatusTooManyRequests, Permanent: false},
+		{Name: "unauthorized", Status: http.StatusUnauthorized, Permanent: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			s, _ := server(t, tc.Status)
+
+			err := (&notify.Webhook{URL: s.URL}).Notify(context.Background(), rendered(t))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), http.StatusText(tc.Status))
+			assert.Equal(t, tc.Permanent, notify.IsPermanent(err))
+		})
+	}
+}
+
+func TestNtfy(t *testing.T) {
+	s, requests := server(t, http.StatusOK)
+
+	nt := &notify.Ntfy{URL: s.URL + "/solar", Token: "tk_secret"}
+	n := rendered(t)
+	require.NoError(t, nt.Notify(context.Background(), n))
+
+	require.Len(t, *requests, 1)
+	r := (*requests)[0]
+	assert.Equal(t, "/solar", r.Path)
+	assert.Equal(t, n.Message, string(r.Body))
+	assert.Equal(t, "[critical] low_soc firing", r.Header.Get("Title"))
+	assert.Equal(t, "5", r.Header.Get("Priority"))
+	assert.Equal(t, "critical,alert", r.Header.Get("Tags"))
+	assert.Equal(t, "Bearer tk_secret", r.Header.Get("Authorization"))
+}
+
+func TestGotify(t *testing.T) {
+	s, requests := server(t, http.StatusOK)
+
+	g := &notify.Gotify{URL: s.URL, Token: "app-token"}
+	n := rendered(t)
+	require.NoError(t, g.Notify(context.Background(), n))
+
+	require.Len(t, *requests, 1)
+	r := (*requests)[0]
+	assert.Equal(t, "/message", r.Path)
+	assert.Equal(t, "app-token", r.Header.Get("X-Gotify-Key"))
+	assert.JSONEq(t, `{"title":"[critical] low_soc firing","message":`+quote(n.Message)+`,"priority":8}`, string(r.Body))
+}
+
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
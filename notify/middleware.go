@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned for notifications dropped by a rate limit.
+var ErrRateLimited = errors.New("rate limited")
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error as not worth retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Retry retries failed notifications up to Attempts times in total, doubling the
+// wait from Backoff after each failure up to MaximumBackoff. Permanent errors and
+// rate limits aren't retried.
+type Retry struct {
+	Notifier       Notifier
+	Attempts       int
+	Backoff        time.Duration
+	MaximumBackoff time.Duration // unlimited when zero
+}
+
+func WithRetry(n Notifier, attempts int, backoff time.Duration) *Retry {
+	return &Retry{Notifier: n, Attempts: attempts, Backoff: backoff}
+}
+
+func (r *Retry) Notify(ctx context.Context, n Notification) error {
+	backoff := r.Backoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = r.Notifier.Notify(ctx, n)
+		if err == nil || IsPermanent(err) || errors.Is(err, ErrRateLimited) || attempt >= r.Attempts {
+			break
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("failed after %d attempts: %w", attempt, errors.Join(err, ctx.Err()))
+		}
+
+		backoff *= 2
+		if r.MaximumBackoff > 0 && backoff > r.MaximumBackoff {
+			backoff = r.MaximumBackoff
+		}
+	}
+
+	return err
+}
+
+// RateLimit passes on at most Burst notifications at once, refilled at one per
+// Interval, and drops the rest with ErrRateLimited. Notifications for the same rule
+// or fault are limited separately when PerName is set, so a flapping rule doesn't
+// hide the others. A zero Interval doesn't limit.
+type RateLimit struct {
+	Notifier Notifier
+	Interval time.Duration
+	Burst    int
+	PerName  bool
+	Clock    func() time.Time // time.Now when nil
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func WithRateLimit(n Notifier, interval time.Duration, burst int) *RateLimit {
+	return &RateLimit{Notifier: n, Interval: interval, Burst: burst}
+}
+
+func (r *RateLimit) Notify(ctx context.Context, n Notification) error {
+	if !r.allow(n) {
+		return fmt.Errorf("dropped %s %s %s: %w", n.Kind, n.Name, n.State, ErrRateLimited)
+	}
+	return r.Notifier.Notify(ctx, n)
+}
+
+func (r *RateLimit) allow(n Notification) bool {
+	if r.Interval <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	if r.Clock != nil {
+		now = r.Clock()
+	}
+
+	key := ""
+	if r.PerName {
+		key = n.Kind + "/" + n.Name
+	}
+
+	burst := float64(r.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.buckets == nil {
+		r.buckets = make(map[string]*bucket)
+	}
+	b, ok := r.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		r.buckets[key] = b
+	}
+
+	b.tokens += float64(now.Sub(b.last)) / float64(r.Interval)
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,95 @@
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/michaelpeterswa/go-renogy-modbus/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flaky fails the first failures notifications with err.
+func flaky(failures int, err error) (notify.Notifier, *int) {
+	calls := 0
+	return notify.NotifierFunc(func(ctx context.Context, n notify.Notification) error {
+		calls++
+		if calls <= failures {
+			return err
+		}
+		return nil
+	}), &calls
+}
+
+func TestRetry(t *testing.T) {
+	unavailable := errors.New("unavailable")
+
+	tests := []struct {
+		Name     string
+		Failures int
+		Err      error
+		Attempts int
+		Calls    int
+		Error    error
+	}{
+		{Name: "first try", Failures: 0, Err: unavailable, Attempts: 3, Calls: 1},
+		{Name: "recovers", Failures: 2, Err: unavailable, Attempts: 3, Calls: 3},
+		{Name: "gives up", Failures: 5, Err: unavailable, Attempts: 3, Calls: 3, Error: unavailable},
+		{Name: "permanent", Failures: 5, Err: notify.Permanent(unavailable), Attempts: 3, Calls: 1, Error: unavailable},
+		{Name: "rate limited", Failures: 5, Err: notify.ErrRateLimited, Attempts: 3, Calls: 1, Error: notify.ErrRateLimited},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			n, calls := flaky(tc.Failures, tc.Err)
+
+			err := notify.WithRetry(n, tc.Attempts, time.Millisecond).Notify(context.Background(), notification())
+			assert.Equal(t, tc.Calls, *calls)
+			if tc.Error == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.Error)
+			}
+		})
+	}
+
+	n, _ := flaky(5, unavailable)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := notify.WithRetry(n, 5, time.Hour).Notify(ctx, notification())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, err, unavailable)
+}
+
+func TestRateLimit(t *testing.T) {
+	now := at
+	n, calls := flaky(0, nil)
+	r := notify.WithRateLimit(n, time.Minute, 2)
+	r.Clock = func() time.Time { return now }
+
+	low, high := notification(), notification()
+	high.Name = "high_temperature"
+
+	require.NoError(t, r.Notify(context.Background(), low))
+	require.NoError(t, r.Notify(context.Background(), high))
+	err := r.Notify(context.Background(), low)
+	assert.ErrorIs(t, err, notify.ErrRateLimited)
+	assert.EqualError(t, err, "dropped alert low_soc firing: rate limited")
+	assert.Equal(t, 2, *calls)
+
+	now = now.Add(30 * time.Second)
+	assert.ErrorIs(t, r.Notify(context.Background(), low), notify.ErrRateLimited)
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, r.Notify(context.Background(), low))
+	assert.Equal(t, 3, *calls)
+
+	// separate buckets per rule
+	r = notify.WithRateLimit(n, time.Minute, 1)
+	r.PerName = true
+	r.Clock = func() time.Time { return now }
+	require.NoError(t, r.Notify(context.Background(), low))
+	require.NoError(t, r.Notify(context.Background(), high))
+	assert.ErrorIs(t, r.Notify(context.Background(), high), notify.ErrRateLimited)
+}
@@ -0,0 +1,164 @@
+// Package notify delivers alert and fault events through pluggable notifiers: a JSON
+// webhook, SMTP email, ntfy and Gotify push and scripts. Notifiers can be wrapped
+// with retries and rate limits, titles and messages are templates over the
+// notification and the reading that caused it.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"text/template"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/alert"
+)
+
+const (
+	KindAlert = "alert"
+	KindFault = "fault"
+)
+
+// Notification is an alert or fault event ready for delivery. Title and Message are
+// rendered by a Dispatcher's templates.
+type Notification struct {
+	Kind        string                                       `json:"kind"`
+	Name        string                                       `json:"name"`  // rule or fault
+	State       string                                       `json:"state"` // firing, resolved, raised or cleared
+	Severity    alert.Severity                               `json:"severity"`
+	Description string                                       `json:"description,omitempty"`
+	Since       time.Time                                    `json:"since"`
+	At          time.Time                                    `json:"at"`
+	Reading     *gorenogymodbus.DynamicControllerInformation `json:"reading,omitempty"`
+	Title       string                                       `json:"title"`
+	Message     string                                       `json:"message"`
+}
+
+func FromAlert(e alert.Event, dci *gorenogymodbus.DynamicControllerInformation) Notification {
+	return Notification{
+		Kind:        KindAlert,
+		Name:        e.Rule,
+		State:       e.State.String(),
+		Severity:    e.Severity,
+		Description: e.Description,
+		Since:       e.Since,
+		At:          e.At,
+		Reading:     dci,
+	}
+}
+
+// FromFault makes a notification of a fault event, raised faults are critical and
+// cleared ones informational.
+func FromFault(e gorenogymodbus.FaultEvent, dci *gorenogymodbus.DynamicControllerInformation) Notification {
+	severity := alert.Critical
+	if e.Kind == gorenogymodbus.FaultCleared {
+		severity = alert.Info
+	}
+
+	return Notification{
+		Kind:     KindFault,
+		Name:     e.Name,
+		State:    e.Kind.String(),
+		Severity: severity,
+		Since:    e.Since,
+		At:       e.At,
+		Reading:  dci,
+	}
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotifierFunc adapts a function to a Notifier.
+type NotifierFunc func(ctx context.Context, n Notification) error
+
+func (f NotifierFunc) Notify(ctx context.Context, n Notification) error {
+	return f(ctx, n)
+}
+
+const (
+	DefaultTitleTemplate   = `[{{.Severity}}] {{.Name}} {{.State}}`
+	DefaultMessageTemplate = `{{.Kind}} {{.Name}} {{.State}} at {{.At.Format "2006-01-02 15:04:05 MST"}}` +
+		`{{with .Description}}: {{.}}{{end}}` +
+		`{{with .Reading}} (battery {{.BatteryCapacitySOC}}% {{.BatteryVoltage}}V, solar {{.ChargingPower}}W, load {{.StreetLightLoadPower}}W){{end}}`
+)
+
+// Templates render the title and message of notifications with text/template.
+type Templates struct {
+	title   *template.Template
+	message *template.Template
+}
+
+func NewTemplates(title, message string) (*Templates, error) {
+	t, err := template.New("title").Parse(title)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse title template: %w", err)
+	}
+
+	m, err := template.New("message").Parse(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message template: %w", err)
+	}
+
+	return &Templates{title: t, message: m}, nil
+}
+
+// DefaultTemplates are DefaultTitleTemplate and DefaultMessageTemplate.
+func DefaultTemplates() *Templates {
+	t, err := NewTemplates(DefaultTitleTemplate, DefaultMessageTemplate)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Render sets the title and message of a notification.
+func (t *Templates) Render(n *Notification) error {
+	var b bytes.Buffer
+	if err := t.title.Execute(&b, n); err != nil {
+		return fmt.Errorf("failed to render title: %w", err)
+	}
+	title := b.String()
+
+	b.Reset()
+	if err := t.message.Execute(&b, n); err != nil {
+		return fmt.Errorf("failed to render message: %w", err)
+	}
+
+	n.Title, n.Message = title, b.String()
+	return nil
+}
+
+// Dispatcher renders notifications and delivers them to every notifier.
+type Dispatcher struct {
+	templates *Templates
+	notifiers []Notifier
+}
+
+// NewDispatcher returns a dispatcher rendering with templates, the default ones
+// when nil.
+func NewDispatcher(templates *Templates, notifiers ...Notifier) *Dispatcher {
+	if templates == nil {
+		templates = DefaultTemplates()
+	}
+	return &Dispatcher{templates: templates, notifiers: notifiers}
+}
+
+// Notify delivers a notification to all notifiers, even when some fail, and returns
+// their joined errors.
+func (d *Dispatcher) Notify(ctx context.Context, n Notification) error {
+	if err := d.templates.Render(&n); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, notifier := range d.notifiers {
+		if err := notifier.Notify(ctx, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
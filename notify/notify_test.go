@@ -0,0 +1,115 @@
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/alert"
+	"github.com/michaelpeterswa/go-renogy-modbus/notify"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var at = time.Date(2023, 8, 10, 21, 30, 0, 0, time.UTC)
+
+func reading() *gorenogymodbus.DynamicControllerInformation {
+	return &gorenogymodbus.DynamicControllerInformation{
+		BatteryCapacitySOC:   18,
+		BatteryVoltage:       decimal.NewFromFloat(11.9),
+		ChargingPower:        decimal.NewFromInt(0),
+		StreetLightLoadPower: decimal.NewFromFloat(20.5),
+	}
+}
+
+func notification() notify.Notification {
+	return notify.FromAlert(alert.Event{
+		Rule:        "low_soc",
+		Severity:    alert.Critical,
+		State:       alert.Firing,
+		Description: "battery is nearly empty",
+		Since:       at.Add(-5 * time.Minute),
+		At:          at,
+	}, reading())
+}
+
+func TestTemplates(t *testing.T) {
+	tests := []struct {
+		Name         string
+		Notification notify.Notification
+		Templates    func() (*notify.Templates, error)
+		Title        string
+		Message      string
+	}{
+		{
+			Name:         "default alert",
+			Notification: notification(),
+			Templates:    func() (*notify.Templates, error) { return notify.DefaultTemplates(), nil },
+			Title:        "[critical] low_soc firing",
+			Message:      "alert low_soc firing at 2023-08-10 21:30:00 UTC: battery is nearly empty (battery 18% 11.9V, solar 0W, load 20.5W)",
+		},
+		{
+			Name: "default fault without reading",
+			Notification: notify.FromFault(gorenogymodbus.FaultEvent{
+				Kind:  gorenogymodbus.FaultCleared,
+				Fault: gorenogymodbus.BatteryUnderVoltage,
+				Name:  gorenogymodbus.BatteryUnderVoltage.String(),
+				At:    at,
+			}, nil),
+			Templates: func() (*notify.Templates, error) { return notify.DefaultTemplates(), nil },
+			Title:     "[info] " + gorenogymodbus.BatteryUnderVoltage.String() + " cleared",
+			Message:   "fault " + gorenogymodbus.BatteryUnderVoltage.String() + " cleared at 2023-08-10 21:30:00 UTC",
+		},
+		{
+			Name:         "custom",
+			Notification: notification(),
+			Templates: func() (*notify.Templates, error) {
+				return notify.NewTemplates("{{.Name}}", "soc {{.Reading.BatteryCapacitySOC}} since {{.Since.Format \"15:04\"}}")
+			},
+			Title:   "low_soc",
+			Message: "soc 18 since 21:25",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			templates, err := tc.Templates()
+			require.NoError(t, err)
+
+			n := tc.Notification
+			require.NoError(t, templates.Render(&n))
+			assert.Equal(t, tc.Title, n.Title)
+			assert.Equal(t, tc.Message, n.Message)
+		})
+	}
+
+	_, err := notify.NewTemplates("{{.Name", "")
+	assert.ErrorContains(t, err, "failed to parse title template")
+
+	templates, err := notify.NewTemplates("{{.Missing}}", "")
+	require.NoError(t, err)
+	n := notification()
+	assert.ErrorContains(t, templates.Render(&n), "failed to render title")
+}
+
+func TestDispatcher(t *testing.T) {
+	var received []notify.Notification
+	ok := notify.NotifierFunc(func(ctx context.Context, n notify.Notification) error {
+		received = append(received, n)
+		return nil
+	})
+	failing := notify.NotifierFunc(func(ctx context.Context, n notify.Notification) error {
+		return errors.New("unreachable")
+	})
+
+	d := notify.NewDispatcher(nil, failing, ok)
+	err := d.Notify(context.Background(), notification())
+	assert.EqualError(t, err, "unreachable")
+
+	require.Len(t, received, 1)
+	assert.Equal(t, "[critical] low_soc firing", received[0].Title)
+	assert.NotEmpty(t, received[0].Message)
+}
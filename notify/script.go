@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Script runs a program for each notification. The notification is written to its
+// standard input as JSON and the main fields are set in the environment as
+// RENOGY_KIND, RENOGY_NAME, RENOGY_STATE, RENOGY_SEVERITY, RENOGY_TITLE and
+// RENOGY_MESSAGE.
+type Script struct {
+	Path string
+	Args []string
+	Env  []string // extra environment, as KEY=value
+}
+
+func (s *Script) Notify(ctx context.Context, n Notification) error {
+	input, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, s.Path, s.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(), s.Env...)
+	cmd.Env = append(cmd.Env,
+		"RENOGY_KIND="+n.Kind,
+		"RENOGY_NAME="+n.Name,
+		"RENOGY_STATE="+n.State,
+		"RENOGY_SEVERITY="+n.Severity.String(),
+		"RENOGY_TITLE="+n.Title,
+		"RENOGY_MESSAGE="+n.Message,
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to run %s: %w: %s", s.Path, err, bytes.TrimSpace(output))
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/michaelpeterswa/go-renogy-modbus/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a posix shell")
+	}
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "notify.sh")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+cat > "$1/input.json"
+printf '%s|%s|%s|%s' "$RENOGY_NAME" "$RENOGY_STATE" "$RENOGY_SEVERITY" "$SITE" > "$1/env"
+`), 0o755))
+
+	n := rendered(t)
+	s := &notify.Script{Path: script, Args: []string{dir}, Env: []string{"SITE=cabin"}}
+	require.NoError(t, s.Notify(context.Background(), n))
+
+	env, err := os.ReadFile(filepath.Join(dir, "env"))
+	require.NoError(t, err)
+	assert.Equal(t, "low_soc|firing|critical|cabin", string(env))
+
+	input, err := os.ReadFile(filepath.Join(dir, "input.json"))
+	require.NoError(t, err)
+	var payload notify.Notification
+	require.NoError(t, json.Unmarshal(input, &payload))
+	assert.Equal(t, n.Title, payload.Title)
+	assert.Equal(t, n.Reading.BatteryCapacitySOC, payload.Reading.BatteryCapacitySOC)
+
+	failing := filepath.Join(dir, "fail.sh")
+	require.NoError(t, os.WriteFile(failing, []byte("#!/bin/sh\necho no route to host\nexit 3\n"), 0o755))
+	err = (&notify.Script{Path: failing}).Notify(context.Background(), n)
+	assert.ErrorContains(t, err, "exit status 3: no route to host")
+}
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTP emails notifications, using the title as subject and the message as body.
+// The connection is upgraded with STARTTLS when the server offers it.
+type SMTP struct {
+	Addr string // host:port
+	Auth smtp.Auth
+	From string
+	To   []string
+}
+
+func (s *SMTP) Notify(ctx context.Context, n Notification) error {
+	if len(s.To) == 0 {
+		return Permanent(fmt.Errorf("no email recipients"))
+	}
+
+	msg := s.message(n)
+
+	// net/smtp has no context support, so give up waiting instead
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.Addr, s.Auth, s.From, s.To, msg)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *SMTP) message(n Notification) []byte {
+	date := n.At
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(n.Message, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+
+	return b.Bytes()
+}
@@ -0,0 +1,106 @@
+package notify_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/michaelpeterswa/go-renogy-modbus/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mail struct {
+	From string
+	To   []string
+	Data string
+}
+
+// smtpServer accepts one session of the minimal SMTP needed by net/smtp, without
+// STARTTLS or AUTH, and sends the mail it received on the returned channel.
+func smtpServer(t *testing.T) (string, <-chan mail) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	mails := make(chan mail, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(format string, args ...interface{}) {
+			fmt.Fprintf(conn, format+"\r\n", args...)
+		}
+
+		var m mail
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+			switch command {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "MAIL":
+				m.From = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+				reply("250 OK")
+			case "RCPT":
+				m.To = append(m.To, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+				reply("250 OK")
+			case "DATA":
+				reply("354 end with .")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				m.Data = data.String()
+				reply("250 OK")
+				mails <- m
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+
+	return l.Addr().String(), mails
+}
+
+func TestSMTP(t *testing.T) {
+	addr, mails := smtpServer(t)
+
+	s := &notify.SMTP{Addr: addr, From: "controller@example.com", To: []string{"me@example.com", "you@example.com"}}
+	n := rendered(t)
+	require.NoError(t, s.Notify(context.Background(), n))
+
+	m := <-mails
+	assert.Equal(t, "controller@example.com", m.From)
+	assert.Equal(t, []string{"me@example.com", "you@example.com"}, m.To)
+	assert.Contains(t, m.Data, "Subject: [critical] low_soc firing\r\n")
+	assert.Contains(t, m.Data, "To: me@example.com, you@example.com\r\n")
+	assert.Contains(t, m.Data, "Date: Thu, 10 Aug 2023 21:30:00 +0000\r\n")
+	assert.True(t, strings.HasSuffix(m.Data, "\r\n\r\n"+n.Message+"\r\n"))
+
+	err := (&notify.SMTP{Addr: addr}).Notify(context.Background(), n)
+	assert.True(t, notify.IsPermanent(err))
+}
@@ -0,0 +1,167 @@
+package gorenogymodbus
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type dynamicField struct {
+	Name     string
+	Address  uint16
+	Quantity uint16
+}
+
+// dynamicFields lists the fields of DynamicControllerInformation in register order,
+// keyed by their json names. 0x10A (light on/off command) is write only and has no field.
+var dynamicFields = []dynamicField{
+	{"battery_capacity_soc", 0x100, 1},
+	{"battery_voltage", 0x101, 1},
+	{"charging_current", 0x102, 1},
+	{"controller_temperature", 0x103, 1},
+	{"battery_temperature", 0x103, 1},
+	{"street_light_load_voltage", 0x104, 1},
+	{"street_light_load_current", 0x105, 1},
+	{"street_light_load_power", 0x106, 1},
+	{"solar_panel_voltage", 0x107, 1},
+	{"solar_panel_current", 0x108, 1},
+	{"charging_power", 0x109, 1},
+	{"battery_minimum_voltage_current_day", 0x10B, 1},
+	{"battery_maximum_voltage_current_day", 0x10C, 1},
+	{"maximum_charging_current_current_day", 0x10D, 1},
+	{"maximum_discharging_current_current_day", 0x10E, 1},
+	{"maximum_charging_power_current_day", 0x10F, 1},
+	{"maximum_discharging_power_current_day", 0x110, 1},
+	{"charging_amp_hours_current_day", 0x111, 1},
+	{"discharging_amp_hours_current_day", 0x112, 1},
+	{"power_generation_current_day", 0x113, 1},
+	{"power_consumption_current_day", 0x114, 1},
+	{"total_operating_days", 0x115, 1},
+	{"total_battery_over_discharges", 0x116, 1},
+	{"total_battery_full_charges", 0x117, 1},
+	{"total_charging_amp_hours", 0x118, 2},
+	{"total_discharging_amp_hours", 0x11A, 2},
+	{"cumulative_power_generation", 0x11C, 2},
+	{"cumulative_power_consumption", 0x11E, 2},
+	{"street_light_status", 0x120, 1},
+	{"street_light_brightness", 0x120, 1},
+	{"charging_state", 0x120, 1},
+	{"controller_faults", 0x121, 2},
+}
+
+// ReadDataRange reads quantity registers of the dynamic controller information block
+// (0x100-0x122) starting at address. The result can be decoded with ParseRange.
+func (mc *ModbusClient) ReadDataRange(address uint16, quantity uint16) ([]byte, error) {
+	err := checkDataRange(address, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := mc.readHoldingRegisters(address, quantity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read holding registers: %w", err)
+	}
+
+	return res, nil
+}
+
+// ParseRange decodes registers read from address onwards. Only fields whose registers
+// are entirely covered by dataBytes are decoded, the rest are left at their zero value
+// and reported as absent by Has.
+func ParseRange(address uint16, dataBytes []byte) (*DynamicControllerInformation, error) {
+	if len(dataBytes)%2 != 0 {
+		return nil, fmt.Errorf("data length is not a whole number of registers: %d", len(dataBytes))
+	}
+
+	quantity := uint16(len(dataBytes) / 2)
+	err := checkDataRange(address, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	end := address + quantity
+	full := make([]byte, int(dataQuantity)*2)
+	for _, f := range dynamicFields {
+		if f.Address < address || f.Address+f.Quantity > end {
+			continue
+		}
+		from := int(f.Address-dataStartAddress) * 2
+		to := from + int(f.Quantity)*2
+		copy(full[from:to], dataBytes[from-int(address-dataStartAddress)*2:])
+	}
+
+	dci, err := Parse(full)
+	if err != nil {
+		return nil, err
+	}
+
+	if address != dataStartAddress || quantity != dataQuantity {
+		dci.partial = true
+		dci.start = address
+		dci.end = end
+		dci.clearAbsent()
+	}
+
+	return dci, nil
+}
+
+// Has reports whether the field with the given json name was decoded. Readings from
+// Parse, or built by hand, have every field.
+func (dci *DynamicControllerInformation) Has(field string) bool {
+	for _, f := range dynamicFields {
+		if f.Name != field {
+			continue
+		}
+		return !dci.partial || (f.Address >= dci.start && f.Address+f.Quantity <= dci.end)
+	}
+	return false
+}
+
+// Field is a field of DynamicControllerInformation named by its json name. Value
+// is an int, decimal.Decimal, bool, string or []string.
+type Field struct {
+	Name  string
+	Value interface{}
+}
+
+// Fields returns the fields of the reading in struct order, leaving out those absent
+// from a partial reading.
+func (dci *DynamicControllerInformation) Fields() []Field {
+	v := reflect.ValueOf(dci).Elem()
+	t := v.Type()
+
+	fields := make([]Field, 0, len(dynamicFields))
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || !dci.Has(name) {
+			continue
+		}
+		fields = append(fields, Field{Name: name, Value: v.Field(i).Interface()})
+	}
+	return fields
+}
+
+// clearAbsent zeroes the fields Parse decoded from the zero-filled registers the
+// reading does not cover, e.g. the "charging deactivated" of a zero 0x120.
+func (dci *DynamicControllerInformation) clearAbsent() {
+	v := reflect.ValueOf(dci).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || dci.Has(name) {
+			continue
+		}
+		v.Field(i).Set(reflect.Zero(t.Field(i).Type))
+	}
+}
+
+func checkDataRange(address uint16, quantity uint16) error {
+	if quantity == 0 {
+		return fmt.Errorf("invalid register quantity: %d", quantity)
+	}
+	if address < dataStartAddress || int(address)+int(quantity) > int(dataStartAddress+dataQuantity) {
+		return fmt.Errorf("register range 0x%X-0x%X is outside of 0x%X-0x%X", address, int(address)+int(quantity)-1, dataStartAddress, dataStartAddress+dataQuantity-1)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package gorenogymodbus_test
+
+import (
+	"testing"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+var sampleBytes = []byte{
+	0x00, 0x64, 0x00, 0x88, 0x00,
+	0x96, 0x19, 0x00, 0x00, 0x88,
+	0x01, 0x90, 0x00, 0x36, 0x00,
+	0xa6, 0x00, 0x6e, 0x00, 0x12,
+	0x00, 0x00, 0x00, 0x00, 0x00,
+	0x84, 0x00, 0x96, 0x01, 0x90,
+	0x00, 0x13, 0x00, 0x0c, 0x00,
+	0x04, 0x00, 0x04, 0x75, 0x30,
+	0x75, 0x30, 0x00, 0x0c, 0x00,
+	0x00, 0x00, 0x0a, 0x00, 0x00,
+	0x00, 0x0a, 0x00, 0x00, 0x00,
+	0x0a, 0x00, 0x01, 0x86, 0xa0,
+	0x00, 0x01, 0x86, 0xa0, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00,
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Address       uint16
+		Bytes         []byte
+		ShouldError   bool
+		PresentFields []string
+		AbsentFields  []string
+	}{
+		{
+			Name:          "first ten registers",
+			Address:       0x100,
+			Bytes:         sampleBytes[0:20],
+			PresentFields: []string{"battery_capacity_soc", "battery_voltage", "solar_panel_current", "charging_power"},
+			AbsentFields:  []string{"battery_minimum_voltage_current_day", "charging_state", "controller_faults"},
+		},
+		{
+			Name:          "whole block",
+			Address:       0x100,
+			Bytes:         sampleBytes,
+			PresentFields: []string{"battery_capacity_soc", "cumulative_power_consumption", "controller_faults"},
+		},
+		{
+			Name:          "split double register",
+			Address:       0x119,
+			Bytes:         sampleBytes[50:56],
+			PresentFields: []string{"total_discharging_amp_hours"},
+			AbsentFields:  []string{"total_charging_amp_hours", "cumulative_power_generation"},
+		},
+		{
+			Name:        "odd length",
+			Address:     0x100,
+			Bytes:       sampleBytes[0:3],
+			ShouldError: true,
+		},
+		{
+			Name:        "outside of block",
+			Address:     0x120,
+			Bytes:       sampleBytes[0:8],
+			ShouldError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			result, err := gorenogymodbus.ParseRange(tc.Address, tc.Bytes)
+			if tc.ShouldError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			for _, f := range tc.PresentFields {
+				assert.True(t, result.Has(f), f)
+			}
+			for _, f := range tc.AbsentFields {
+				assert.False(t, result.Has(f), f)
+			}
+		})
+	}
+}
+
+func TestParseRangeValues(t *testing.T) {
+	result, err := gorenogymodbus.ParseRange(0x100, sampleBytes[0:20])
+	assert.NoError(t, err)
+
+	assert.Equal(t, 100, result.BatteryCapacitySOC)
+	assert.True(t, decimal.NewFromFloat(13.6).Equal(result.BatteryVoltage))
+	assert.True(t, decimal.NewFromFloat(18).Equal(result.ChargingPower))
+	assert.True(t, result.TotalChargingAmpHours.IsZero())
+	assert.Nil(t, result.ControllerFaults)
+	assert.Empty(t, result.ChargingState)
+	assert.False(t, result.StreetLightStatus)
+}
+
+func TestFields(t *testing.T) {
+	full, err := gorenogymodbus.Parse(sampleBytes)
+	assert.NoError(t, err)
+	fields := full.Fields()
+	assert.Len(t, fields, 32)
+	assert.Equal(t, gorenogymodbus.Field{Name: "battery_capacity_soc", Value: 100}, fields[0])
+	assert.Equal(t, "controller_faults", fields[len(fields)-1].Name)
+
+	partial, err := gorenogymodbus.ParseRange(0x100, sampleBytes[0:4])
+	assert.NoError(t, err)
+	fields = partial.Fields()
+	if assert.Len(t, fields, 2) {
+		assert.Equal(t, "battery_voltage", fields[1].Name)
+		assert.True(t, decimal.NewFromFloat(13.6).Equal(fields[1].Value.(decimal.Decimal)))
+	}
+}
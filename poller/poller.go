@@ -0,0 +1,385 @@
+// Package poller polls the register blocks of a controller on their own schedules,
+// such as readings every few seconds and settings every few minutes, and delivers
+// the results to subscribers. Reads share the bus one at a time and concurrent reads
+// of the same block are coalesced into one.
+package poller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+)
+
+type Block int
+
+const (
+	Dynamic  Block = iota // readings, 0x100
+	Product               // product information, 0x0A
+	Settings              // battery and load settings, 0xE002
+	History               // daily history, 0xF000
+)
+
+func (b Block) String() string {
+	switch b {
+	case Dynamic:
+		return "dynamic"
+	case Product:
+		return "product"
+	case Settings:
+		return "settings"
+	case History:
+		return "history"
+	default:
+		return "unknown"
+	}
+}
+
+func (b Block) valid() bool {
+	return b >= Dynamic && b <= History
+}
+
+// Schedule is how often a block is read.
+type Schedule struct {
+	Block    Block
+	Interval time.Duration
+	Days     int // days of history read, defaults to gorenogymodbus.MaximumHistoryDays
+}
+
+// DefaultSchedules reads readings every 5 seconds, settings every 15 minutes,
+// product information hourly and history daily.
+func DefaultSchedules() []Schedule {
+	return []Schedule{
+		{Block: Dynamic, Interval: 5 * time.Second},
+		{Block: Settings, Interval: 15 * time.Minute},
+		{Block: Product, Interval: time.Hour},
+		{Block: History, Interval: 24 * time.Hour},
+	}
+}
+
+// Result is a read of a block, only the field of the block is set. Time is when the
+// read started and Latency how long it took. History is ordered like the controller
+// keeps it, today first.
+type Result struct {
+	Block    Block
+	Time     time.Time
+	Latency  time.Duration
+	Err      error
+	Dynamic  *gorenogymodbus.DynamicControllerInformation
+	Product  *gorenogymodbus.ProductInformation
+	Settings *gorenogymodbus.Settings
+	History  []*gorenogymodbus.DailyTotals
+}
+
+type Config struct {
+	Schedules      []Schedule    // defaults to DefaultSchedules
+	Backoff        time.Duration // wait after the first failed read of a block, defaults to 1 second
+	MaximumBackoff time.Duration // defaults to 1 minute
+}
+
+// Poller reads blocks on their schedules. A failed read is retried after Backoff,
+// doubling with each further failure up to MaximumBackoff, until a read succeeds
+// and the block is back on its schedule.
+type Poller struct {
+	mc     *gorenogymodbus.ModbusClient
+	config Config
+	days   map[Block]int
+
+	bus sync.Mutex
+
+	mu          sync.Mutex
+	calls       map[Block]*call
+	subscribers map[int]*subscriber
+	next        int
+}
+
+type call struct {
+	done   chan struct{}
+	result Result
+}
+
+type subscriber struct {
+	blocks map[Block]bool // all blocks when empty
+	ch     chan Result
+	fn     func(Result)
+}
+
+func New(mc *gorenogymodbus.ModbusClient, config Config) (*Poller, error) {
+	if len(config.Schedules) == 0 {
+		config.Schedules = DefaultSchedules()
+	}
+	if config.Backoff == 0 {
+		config.Backoff = time.Second
+	}
+	if config.MaximumBackoff == 0 {
+		config.MaximumBackoff = time.Minute
+	}
+
+	days := make(map[Block]int)
+	seen := make(map[Block]bool)
+	for i, s := range config.Schedules {
+		if !s.Block.valid() {
+			return nil, fmt.Errorf("invalid block: %d", s.Block)
+		}
+		if seen[s.Block] {
+			return nil, fmt.Errorf("duplicate schedule for block: %s", s.Block)
+		}
+		seen[s.Block] = true
+		if s.Interval <= 0 {
+			return nil, fmt.Errorf("invalid interval for block %s: %s", s.Block, s.Interval)
+		}
+		if s.Days == 0 {
+			config.Schedules[i].Days = gorenogymodbus.MaximumHistoryDays
+		} else if s.Days < 0 || s.Days > gorenogymodbus.MaximumHistoryDays {
+			return nil, fmt.Errorf("invalid history days: %d", s.Days)
+		}
+		days[s.Block] = config.Schedules[i].Days
+	}
+
+	return &Poller{
+		mc:          mc,
+		config:      config,
+		days:        days,
+		calls:       make(map[Block]*call),
+		subscribers: make(map[int]*subscriber),
+	}, nil
+}
+
+// Subscribe returns a channel receiving results of blocks, or of all blocks when
+// none are given, and a function to unsubscribe. Results are dropped while the
+// channel is full so a slow subscriber doesn't hold up the bus. The channel is
+// closed when unsubscribing or when Run returns.
+func (p *Poller) Subscribe(buffer int, blocks ...Block) (<-chan Result, func()) {
+	ch := make(chan Result, buffer)
+	return ch, p.subscribe(&subscriber{blocks: blockSet(blocks), ch: ch})
+}
+
+// OnResult calls fn with results of blocks, or of all blocks when none are given,
+// and returns a function to stop. fn is called from the reading goroutine and
+// should return quickly.
+func (p *Poller) OnResult(fn func(Result), blocks ...Block) func() {
+	return p.subscribe(&subscriber{blocks: blockSet(blocks), fn: fn})
+}
+
+func blockSet(blocks []Block) map[Block]bool {
+	set := make(map[Block]bool)
+	for _, b := range blocks {
+		set[b] = true
+	}
+	return set
+}
+
+func (p *Poller) subscribe(s *subscriber) func() {
+	p.mu.Lock()
+	id := p.next
+	p.next++
+	p.subscribers[id] = s
+	p.mu.Unlock()
+
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.unsubscribe(id)
+	}
+}
+
+// unsubscribe must be called with mu held.
+func (p *Poller) unsubscribe(id int) {
+	s, ok := p.subscribers[id]
+	if !ok {
+		return
+	}
+	delete(p.subscribers, id)
+	if s.ch != nil {
+		close(s.ch)
+	}
+}
+
+func (p *Poller) publish(r Result) {
+	var fns []func(Result)
+
+	p.mu.Lock()
+	for _, s := range p.subscribers {
+		if len(s.blocks) > 0 && !s.blocks[r.Block] {
+			continue
+		}
+		if s.fn != nil {
+			fns = append(fns, s.fn)
+			continue
+		}
+		select {
+		case s.ch <- r:
+		default:
+		}
+	}
+	p.mu.Unlock()
+
+	// outside the lock so callbacks can unsubscribe
+	for _, fn := range fns {
+		fn(r)
+	}
+}
+
+type scheduleState struct {
+	Schedule
+	next     time.Time
+	failures int
+}
+
+// Run reads all blocks once and then on their schedules until ctx is done, then
+// closes the subscription channels and returns ctx.Err().
+func (p *Poller) Run(ctx context.Context) error {
+	defer func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for id := range p.subscribers {
+			p.unsubscribe(id)
+		}
+	}()
+
+	now := time.Now()
+	states := make([]*scheduleState, len(p.config.Schedules))
+	for i, s := range p.config.Schedules {
+		states[i] = &scheduleState{Schedule: s, next: now}
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	for {
+		for _, s := range states {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if time.Now().Before(s.next) {
+				continue
+			}
+
+			start := time.Now()
+			r := p.do(s.Block)
+			if r.Err != nil {
+				s.failures++
+				s.next = time.Now().Add(p.backoff(s.failures))
+				continue
+			}
+			s.failures = 0
+			s.next = start.Add(s.Interval)
+		}
+
+		next := states[0].next
+		for _, s := range states[1:] {
+			if s.next.Before(next) {
+				next = s.next
+			}
+		}
+
+		timer.Reset(time.Until(next))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *Poller) backoff(failures int) time.Duration {
+	backoff := p.config.Backoff
+	for i := 1; i < failures && backoff < p.config.MaximumBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.config.MaximumBackoff {
+		backoff = p.config.MaximumBackoff
+	}
+	return backoff
+}
+
+// Read reads a block now, outside its schedule, and publishes the result to
+// subscribers. A read of the block already in progress is shared instead.
+func (p *Poller) Read(ctx context.Context, block Block) (Result, error) {
+	if !block.valid() {
+		return Result{}, fmt.Errorf("invalid block: %d", block)
+	}
+
+	done := make(chan Result, 1)
+	go func() {
+		done <- p.do(block)
+	}()
+
+	select {
+	case r := <-done:
+		return r, r.Err
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	}
+}
+
+// Exclusive calls fn with the client while no block is being read, for writes and
+// other requests sharing the bus with the poller.
+func (p *Poller) Exclusive(fn func(mc *gorenogymodbus.ModbusClient) error) error {
+	p.bus.Lock()
+	defer p.bus.Unlock()
+	return fn(p.mc)
+}
+
+// do reads a block, or waits for the read of it in progress, and publishes the
+// result once.
+func (p *Poller) do(block Block) Result {
+	p.mu.Lock()
+	if c, ok := p.calls[block]; ok {
+		p.mu.Unlock()
+		<-c.done
+		return c.result
+	}
+	c := &call{done: make(chan struct{})}
+	p.calls[block] = c
+	p.mu.Unlock()
+
+	p.bus.Lock()
+	c.result = p.read(block)
+	p.bus.Unlock()
+
+	p.mu.Lock()
+	delete(p.calls, block)
+	p.mu.Unlock()
+	close(c.done)
+
+	p.publish(c.result)
+	return c.result
+}
+
+func (p *Poller) read(block Block) Result {
+	r := Result{Block: block, Time: time.Now()}
+
+	switch block {
+	case Dynamic:
+		var res []byte
+		res, r.Err = p.mc.ReadData()
+		if r.Err == nil {
+			r.Dynamic, r.Err = gorenogymodbus.Parse(res)
+		}
+	case Product:
+		r.Product, r.Err = p.mc.ReadProductInformation()
+	case Settings:
+		r.Settings, r.Err = p.mc.ReadSettings()
+	case History:
+		days, ok := p.days[History]
+		if !ok {
+			days = gorenogymodbus.MaximumHistoryDays
+		}
+		for day := 0; day < days; day++ {
+			totals, err := p.mc.ReadDailyHistory(day)
+			if err != nil {
+				r.Err = fmt.Errorf("failed to read history day %d: %w", day, err)
+				r.History = nil
+				break
+			}
+			r.History = append(r.History, totals)
+		}
+	}
+
+	r.Latency = time.Since(r.Time)
+	return r
+}
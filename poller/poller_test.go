@@ -0,0 +1,219 @@
+package poller_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/poller"
+	"github.com/michaelpeterswa/go-renogy-modbus/simulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingModel counts the reads of the dynamic block reaching the device.
+type countingModel struct {
+	*simulator.Model
+	reads atomic.Int32
+}
+
+func (m *countingModel) ReadHoldingRegisters(address, quantity uint16) ([]byte, error) {
+	if address == 0x100 {
+		m.reads.Add(1)
+	}
+	return m.Model.ReadHoldingRegisters(address, quantity)
+}
+
+func newServer(t *testing.T) (*simulator.Server, *countingModel) {
+	now := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	m := &countingModel{Model: simulator.NewModel(simulator.ModelConfig{Clock: func() time.Time { return now }})}
+	s := simulator.NewServer(1, m)
+	s.Injector = simulator.NewInjector(1)
+	return s, m
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Schedules []poller.Schedule
+		Error     string
+	}{
+		{Name: "defaults"},
+		{
+			Name:      "invalid block",
+			Schedules: []poller.Schedule{{Block: 7, Interval: time.Second}},
+			Error:     "invalid block: 7",
+		},
+		{
+			Name:      "duplicate",
+			Schedules: []poller.Schedule{{Block: poller.Dynamic, Interval: time.Second}, {Block: poller.Dynamic, Interval: time.Minute}},
+			Error:     "duplicate schedule for block: dynamic",
+		},
+		{
+			Name:      "interval",
+			Schedules: []poller.Schedule{{Block: poller.Settings}},
+			Error:     "invalid interval for block settings: 0s",
+		},
+		{
+			Name:      "history days",
+			Schedules: []poller.Schedule{{Block: poller.History, Interval: time.Hour, Days: 31}},
+			Error:     "invalid history days: 31",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := poller.New(&gorenogymodbus.ModbusClient{}, poller.Config{Schedules: tc.Schedules})
+			if tc.Error == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.Error)
+			}
+		})
+	}
+}
+
+func TestPoller(t *testing.T) {
+	s, _ := newServer(t)
+	p, err := poller.New(&gorenogymodbus.ModbusClient{Client: s.Client()}, poller.Config{
+		Schedules: []poller.Schedule{
+			{Block: poller.Dynamic, Interval: 10 * time.Millisecond},
+			{Block: poller.Product, Interval: time.Hour},
+			{Block: poller.Settings, Interval: time.Hour},
+			{Block: poller.History, Interval: time.Hour, Days: 2},
+		},
+	})
+	require.NoError(t, err)
+
+	results, unsubscribe := p.Subscribe(100)
+	defer unsubscribe()
+	settings, _ := p.Subscribe(10, poller.Settings)
+
+	var mu sync.Mutex
+	var readings []*gorenogymodbus.DynamicControllerInformation
+	p.OnResult(func(r poller.Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		readings = append(readings, r.Dynamic)
+	}, poller.Dynamic)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, p.Run(ctx), context.DeadlineExceeded)
+
+	counts := make(map[poller.Block]int)
+	for r := range results {
+		require.NoError(t, r.Err)
+		assert.False(t, r.Time.IsZero())
+		counts[r.Block]++
+
+		switch r.Block {
+		case poller.Dynamic:
+			assert.Equal(t, 60, r.Dynamic.BatteryCapacitySOC)
+		case poller.Product:
+			assert.Equal(t, "RNG-CTRL-RVR40", r.Product.Model)
+		case poller.Settings:
+			assert.Equal(t, 100, r.Settings.NominalBatteryCapacity)
+		case poller.History:
+			assert.Len(t, r.History, 2)
+		}
+	}
+
+	assert.GreaterOrEqual(t, counts[poller.Dynamic], 5)
+	assert.Equal(t, 1, counts[poller.Product])
+	assert.Equal(t, 1, counts[poller.Settings])
+	assert.Equal(t, 1, counts[poller.History])
+
+	r, ok := <-settings
+	assert.True(t, ok)
+	assert.Equal(t, poller.Settings, r.Block)
+	_, ok = <-settings
+	assert.False(t, ok, "closed when Run returns")
+
+	mu.Lock()
+	assert.Len(t, readings, counts[poller.Dynamic])
+	mu.Unlock()
+}
+
+func TestPollerBackoff(t *testing.T) {
+	s, _ := newServer(t)
+	s.Injector.Add(simulator.Rule{
+		Injection: simulator.ExceptionResponse,
+		Exception: simulator.ExceptionServerDeviceFailure,
+		Match:     func(function byte, address uint16) bool { return address == 0x100 },
+		Count:     3,
+	})
+
+	p, err := poller.New(&gorenogymodbus.ModbusClient{Client: s.Client()}, poller.Config{
+		Schedules:      []poller.Schedule{{Block: poller.Dynamic, Interval: time.Hour}},
+		Backoff:        10 * time.Millisecond,
+		MaximumBackoff: 20 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	results, _ := p.Subscribe(10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	go p.Run(ctx) //nolint:errcheck
+
+	var got []poller.Result
+	for r := range results {
+		got = append(got, r)
+		if r.Err == nil {
+			cancel()
+		}
+	}
+
+	require.Len(t, got, 4)
+	for _, r := range got[:3] {
+		assert.ErrorContains(t, r.Err, "exception '4'")
+	}
+	require.NoError(t, got[3].Err)
+	assert.NotNil(t, got[3].Dynamic)
+
+	assert.GreaterOrEqual(t, got[1].Time.Sub(got[0].Time), 10*time.Millisecond)
+	assert.GreaterOrEqual(t, got[2].Time.Sub(got[1].Time), 20*time.Millisecond)
+	assert.GreaterOrEqual(t, got[3].Time.Sub(got[2].Time), 20*time.Millisecond)
+	assert.Less(t, got[3].Time.Sub(got[2].Time), 100*time.Millisecond, "capped at MaximumBackoff")
+}
+
+func TestPollerCoalesce(t *testing.T) {
+	s, m := newServer(t)
+	s.Injector.Add(simulator.Rule{Injection: simulator.DelayResponse, Delay: 50 * time.Millisecond, Count: 1})
+
+	p, err := poller.New(&gorenogymodbus.ModbusClient{Client: s.Client()}, poller.Config{})
+	require.NoError(t, err)
+	results, _ := p.Subscribe(10)
+
+	var wg sync.WaitGroup
+	readings := make([]poller.Result, 3)
+	for i := range readings {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r, err := p.Read(context.Background(), poller.Dynamic)
+			assert.NoError(t, err)
+			readings[i] = r
+		}(i)
+		time.Sleep(5 * time.Millisecond)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), m.reads.Load())
+	assert.Equal(t, readings[0], readings[1])
+	assert.Equal(t, readings[0], readings[2])
+	assert.Len(t, results, 1, "published once")
+	assert.GreaterOrEqual(t, readings[0].Latency, 50*time.Millisecond)
+
+	// writes wait for the bus
+	require.NoError(t, p.Exclusive(func(mc *gorenogymodbus.ModbusClient) error {
+		return mc.SetLoad(true)
+	}))
+	assert.True(t, m.Reading().StreetLightStatus)
+
+	_, err = p.Read(context.Background(), 9)
+	assert.EqualError(t, err, "invalid block: 9")
+}
@@ -0,0 +1,128 @@
+package gorenogymodbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+const (
+	productInformationAddress  uint16 = 0x0A
+	productInformationQuantity uint16 = 17
+)
+
+type ProductType int
+
+const (
+	ProductTypeController ProductType = iota
+	ProductTypeInverter
+)
+
+func (pt ProductType) String() string {
+	switch pt {
+	case ProductTypeController:
+		return "controller"
+	case ProductTypeInverter:
+		return "inverter"
+	default:
+		return "unknown"
+	}
+}
+
+type ProductInformation struct {
+	MaximumVoltageSupported int    `json:"maximum_voltage_supported"` // 0x0A (eight higher bits)
+	RatedChargingCurrent    int    `json:"rated_charging_current"`    // 0x0A (eight lower bits)
+	RatedDischargingCurrent int    `json:"rated_discharging_current"` // 0x0B (eight higher bits)
+	ProductType             string `json:"product_type"`              // 0x0B (eight lower bits)
+	Model                   string `json:"model"`                     // 0x0C-0x13
+	SoftwareVersion         string `json:"software_version"`          // 0x14-0x15
+	HardwareVersion         string `json:"hardware_version"`          // 0x16-0x17
+	SerialNumber            string `json:"serial_number"`             // 0x18-0x19
+	DeviceAddress           int    `json:"device_address"`            // 0x1A
+}
+
+// ReadProductInformation reads the product information block (0x0A-0x1A).
+func (mc *ModbusClient) ReadProductInformation() (*ProductInformation, error) {
+	res, err := mc.readHoldingRegisters(productInformationAddress, productInformationQuantity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read holding registers: %w", err)
+	}
+
+	return ParseProductInformation(res)
+}
+
+func ParseProductInformation(dataBytes []byte) (*ProductInformation, error) {
+	if len(dataBytes) != int(productInformationQuantity)*2 {
+		return nil, fmt.Errorf("data length is not %d bytes: %d", productInformationQuantity*2, len(dataBytes))
+	}
+
+	return &ProductInformation{
+		MaximumVoltageSupported: int(dataBytes[0]),                                            // 0x0A first byte
+		RatedChargingCurrent:    int(dataBytes[1]),                                            // 0x0A second byte
+		RatedDischargingCurrent: int(dataBytes[2]),                                            // 0x0B first byte
+		ProductType:             ProductType(dataBytes[3]).String(),                           // 0x0B second byte
+		Model:                   strings.Trim(string(dataBytes[4:20]), " \x00"),               // 0x0C-0x13
+		SoftwareVersion:         parseVersion(dataBytes[20:24]),                               // 0x14-0x15
+		HardwareVersion:         parseVersion(dataBytes[24:28]),                               // 0x16-0x17
+		SerialNumber:            fmt.Sprintf("%d", binary.BigEndian.Uint32(dataBytes[28:32])), // 0x18-0x19
+		DeviceAddress:           int(binary.BigEndian.Uint16(dataBytes[32:34])),               // 0x1A
+	}, nil
+}
+
+func (pi *ProductInformation) Synthesize() ([]byte, error) {
+	var data []byte
+
+	data = append(data, byte(pi.MaximumVoltageSupported), byte(pi.RatedChargingCurrent))
+	data = append(data, byte(pi.RatedDischargingCurrent), byte(productTypeFromString(pi.ProductType)))
+
+	if len(pi.Model) > 16 {
+		return nil, fmt.Errorf("invalid model length: %d", len(pi.Model))
+	}
+	data = append(data, []byte(fmt.Sprintf("%16s", pi.Model))...) // right aligned like the controller
+
+	for _, v := range []string{pi.SoftwareVersion, pi.HardwareVersion} {
+		b, err := synthesizeVersion(v)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, b...)
+	}
+
+	var serial uint32
+	_, err := fmt.Sscanf(pi.SerialNumber, "%d", &serial)
+	if err != nil {
+		return nil, fmt.Errorf("invalid serial number: %s", pi.SerialNumber)
+	}
+	data = binary.BigEndian.AppendUint32(data, serial)
+	data = binary.BigEndian.AppendUint16(data, uint16(pi.DeviceAddress))
+
+	if len(data) != int(productInformationQuantity)*2 {
+		return nil, fmt.Errorf("invalid product information byte slice length: %d", len(data))
+	}
+	return data, nil
+}
+
+func productTypeFromString(s string) ProductType {
+	switch s {
+	case "controller":
+		return ProductTypeController
+	case "inverter":
+		return ProductTypeInverter
+	default:
+		return -1
+	}
+}
+
+// parseVersion decodes a version register pair, the first byte is reserved.
+func parseVersion(b []byte) string {
+	return fmt.Sprintf("V%d.%d.%d", b[1], b[2], b[3])
+}
+
+func synthesizeVersion(s string) ([]byte, error) {
+	var major, minor, patch uint8
+	_, err := fmt.Sscanf(s, "V%d.%d.%d", &major, &minor, &patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version: %s", s)
+	}
+	return []byte{0x00, major, minor, patch}, nil
+}
@@ -0,0 +1,41 @@
+package gorenogymodbus_test
+
+import (
+	"testing"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductInformation(t *testing.T) {
+	bytes := []byte{
+		0x18, 0x28, 0x14, 0x00, 0x20,
+		0x20, 0x52, 0x4e, 0x47, 0x2d,
+		0x43, 0x54, 0x52, 0x4c, 0x2d,
+		0x52, 0x56, 0x52, 0x34, 0x30,
+		0x00, 0x01, 0x00, 0x02, 0x00,
+		0x01, 0x00, 0x00, 0x00, 0x01,
+		0xe2, 0x40, 0x00, 0x01,
+	}
+
+	pi, err := gorenogymodbus.ParseProductInformation(bytes)
+	assert.NoError(t, err)
+	assert.Equal(t, &gorenogymodbus.ProductInformation{
+		MaximumVoltageSupported: 24,
+		RatedChargingCurrent:    40,
+		RatedDischargingCurrent: 20,
+		ProductType:             gorenogymodbus.ProductTypeController.String(),
+		Model:                   "RNG-CTRL-RVR40",
+		SoftwareVersion:         "V1.0.2",
+		HardwareVersion:         "V1.0.0",
+		SerialNumber:            "123456",
+		DeviceAddress:           1,
+	}, pi)
+
+	result, err := pi.Synthesize()
+	assert.NoError(t, err)
+	assert.Equal(t, bytes, result)
+
+	_, err = gorenogymodbus.ParseProductInformation(bytes[:20])
+	assert.Error(t, err)
+}
@@ -0,0 +1,226 @@
+package gorenogymodbus
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// Profile is a named set of charging parameters: the system voltage, battery type,
+// the charge and discharge voltages (on a 12V basis like Settings) and durations
+// of 0xE003-0xE014. Capacity, end of charge and discharge SOC and the load working
+// mode are left to the installation.
+type Profile struct {
+	Name        string
+	Description string
+	Settings    Settings
+}
+
+// chargeProfile holds the 12V parameters of a chemistry, scaled to a system
+// voltage by profiles.
+type chargeProfile struct {
+	name        string
+	description string
+	batteryType BatteryType
+	// over voltage, charging limit, equalizing, boost, float, boost recovery, over
+	// discharge recovery, under voltage warning, over discharge, discharging limit
+	voltages                [10]float64
+	equalizingTime          int // minutes, zero disables equalization
+	boostTime               int // minutes
+	equalizingInterval      int // days
+	temperatureCompensation int // mV/°C/2V
+}
+
+var chargeProfiles = []chargeProfile{
+	{
+		name:                    "flooded",
+		description:             "flooded lead acid",
+		batteryType:             FloodedBattery,
+		voltages:                [10]float64{16.0, 15.5, 14.8, 14.6, 13.8, 13.2, 12.6, 12.0, 11.1, 10.6},
+		equalizingTime:          120,
+		boostTime:               120,
+		equalizingInterval:      30,
+		temperatureCompensation: 3,
+	},
+	{
+		name:                    "agm",
+		description:             "absorbed glass mat (sealed) lead acid",
+		batteryType:             SealedBattery,
+		voltages:                [10]float64{16.0, 15.5, 14.6, 14.4, 13.8, 13.2, 12.6, 12.0, 11.1, 10.6},
+		equalizingTime:          120,
+		boostTime:               120,
+		equalizingInterval:      30,
+		temperatureCompensation: 3,
+	},
+	{
+		name:                    "gel",
+		description:             "gel lead acid, never equalized",
+		batteryType:             GelBattery,
+		voltages:                [10]float64{16.0, 15.5, 14.2, 14.2, 13.8, 13.2, 12.6, 12.0, 11.1, 10.6},
+		boostTime:               120,
+		temperatureCompensation: 3,
+	},
+	{
+		name:        "lifepo4",
+		description: "generic lithium iron phosphate, conservative user defined values",
+		batteryType: UserDefinedBattery,
+		voltages:    [10]float64{14.8, 14.6, 14.2, 14.2, 13.5, 13.2, 12.6, 12.0, 11.0, 10.6},
+		boostTime:   60,
+	},
+	{
+		name:        "renogy-lithium",
+		description: "Renogy lithium iron phosphate batteries, values recommended by Renogy",
+		batteryType: LithiumBattery,
+		voltages:    [10]float64{15.0, 14.6, 14.4, 14.4, 13.6, 13.2, 12.6, 12.0, 11.0, 10.6},
+		boostTime:   120,
+	},
+}
+
+var profileSystemVoltages = []int{12, 24, 48}
+
+// Profiles returns the preset profiles of every chemistry for 12, 24 and 48V
+// systems, named like "agm-24v", sorted by name.
+func Profiles() []Profile {
+	var profiles []Profile
+	for _, cp := range chargeProfiles {
+		for _, systemVoltage := range profileSystemVoltages {
+			profiles = append(profiles, cp.profile(systemVoltage))
+		}
+	}
+
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
+
+// LookupProfile returns the preset profile of a name.
+func LookupProfile(name string) (Profile, error) {
+	for _, p := range Profiles() {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("unknown profile: %s", name)
+}
+
+func (cp chargeProfile) profile(systemVoltage int) Profile {
+	v := make([]decimal.Decimal, len(cp.voltages))
+	for i, f := range cp.voltages {
+		v[i] = decimal.NewFromFloat(f)
+	}
+
+	return Profile{
+		Name:        fmt.Sprintf("%s-%dv", cp.name, systemVoltage),
+		Description: fmt.Sprintf("%dV %s", systemVoltage, cp.description),
+		Settings: Settings{
+			SystemVoltage:                 systemVoltage,
+			BatteryType:                   cp.batteryType.String(),
+			OverVoltageThreshold:          v[0],
+			ChargingLimitVoltage:          v[1],
+			EqualizingChargingVoltage:     v[2],
+			BoostChargingVoltage:          v[3],
+			FloatingChargingVoltage:       v[4],
+			BoostChargingRecoveryVoltage:  v[5],
+			OverDischargeRecoveryVoltage:  v[6],
+			UnderVoltageWarningLevel:      v[7],
+			OverDischargeVoltage:          v[8],
+			DischargingLimitVoltage:       v[9],
+			OverDischargeTimeDelay:        5,
+			EqualizingChargingTime:        cp.equalizingTime,
+			BoostChargingTime:             cp.boostTime,
+			EqualizingChargingInterval:    cp.equalizingInterval,
+			TemperatureCompensationFactor: cp.temperatureCompensation,
+		},
+	}
+}
+
+// ProfileFields are the json names of the settings a profile sets.
+var ProfileFields = []string{
+	"system_voltage",
+	"battery_type",
+	"over_voltage_threshold",
+	"charging_limit_voltage",
+	"equalizing_charging_voltage",
+	"boost_charging_voltage",
+	"floating_charging_voltage",
+	"boost_charging_recovery_voltage",
+	"over_discharge_recovery_voltage",
+	"under_voltage_warning_level",
+	"over_discharge_voltage",
+	"discharging_limit_voltage",
+	"over_discharge_time_delay",
+	"equalizing_charging_time",
+	"boost_charging_time",
+	"equalizing_charging_interval",
+	"temperature_compensation_factor",
+}
+
+// Validate checks the profile's parameters like Settings.Validate does.
+func (p Profile) Validate() error {
+	if err := errors.Join(p.Settings.validateCharging()...); err != nil {
+		return fmt.Errorf("invalid profile %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+// Derive returns a custom profile of a name with overrides applied to a copy of the
+// profile's settings. The result must still validate.
+func (p Profile) Derive(name string, overrides func(s *Settings)) (Profile, error) {
+	derived := Profile{
+		Name:        name,
+		Description: fmt.Sprintf("derived from %s", p.Name),
+		Settings:    p.Settings,
+	}
+	overrides(&derived.Settings)
+
+	if err := derived.Validate(); err != nil {
+		return Profile{}, err
+	}
+	return derived, nil
+}
+
+// Apply returns the current settings with the profile's parameters, keeping the
+// capacity, recognized voltage, end of charge and discharge SOC and load working mode.
+func (p Profile) Apply(current *Settings) (*Settings, error) {
+	s := p.Merge(current)
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid settings with profile %s: %w", p.Name, err)
+	}
+	return s, nil
+}
+
+// Merge is Apply without validating, for callers that change the settings further
+// before checking them.
+func (p Profile) Merge(current *Settings) *Settings {
+	ps := p.Settings
+	s := *current
+	s.SystemVoltage = ps.SystemVoltage
+	s.BatteryType = ps.BatteryType
+	s.OverVoltageThreshold = ps.OverVoltageThreshold
+	s.ChargingLimitVoltage = ps.ChargingLimitVoltage
+	s.EqualizingChargingVoltage = ps.EqualizingChargingVoltage
+	s.BoostChargingVoltage = ps.BoostChargingVoltage
+	s.FloatingChargingVoltage = ps.FloatingChargingVoltage
+	s.BoostChargingRecoveryVoltage = ps.BoostChargingRecoveryVoltage
+	s.OverDischargeRecoveryVoltage = ps.OverDischargeRecoveryVoltage
+	s.UnderVoltageWarningLevel = ps.UnderVoltageWarningLevel
+	s.OverDischargeVoltage = ps.OverDischargeVoltage
+	s.DischargingLimitVoltage = ps.DischargingLimitVoltage
+	s.OverDischargeTimeDelay = ps.OverDischargeTimeDelay
+	s.EqualizingChargingTime = ps.EqualizingChargingTime
+	s.BoostChargingTime = ps.BoostChargingTime
+	s.EqualizingChargingInterval = ps.EqualizingChargingInterval
+	s.TemperatureCompensationFactor = ps.TemperatureCompensationFactor
+	return &s
+}
+
+// Writes returns the settings writes that apply the profile to a controller with
+// the current settings.
+func (p Profile) Writes(current *Settings) ([]SettingsWrite, error) {
+	desired, err := p.Apply(current)
+	if err != nil {
+		return nil, err
+	}
+	return PlanSettingsWrites(current, desired)
+}
@@ -0,0 +1,101 @@
+package gorenogymodbus_test
+
+import (
+	"testing"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfiles(t *testing.T) {
+	profiles := gorenogymodbus.Profiles()
+	require.Len(t, profiles, 15)
+	assert.Equal(t, "agm-12v", profiles[0].Name)
+
+	for _, p := range profiles {
+		t.Run(p.Name, func(t *testing.T) {
+			assert.NoError(t, p.Validate())
+
+			current := sealedSettings()
+			_, err := p.Apply(&current)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestLookupProfile(t *testing.T) {
+	p, err := gorenogymodbus.LookupProfile("renogy-lithium-24v")
+	require.NoError(t, err)
+	assert.Equal(t, 24, p.Settings.SystemVoltage)
+	assert.Equal(t, gorenogymodbus.LithiumBattery.String(), p.Settings.BatteryType)
+
+	_, err = gorenogymodbus.LookupProfile("lead-12v")
+	assert.EqualError(t, err, "unknown profile: lead-12v")
+}
+
+func TestProfileDerive(t *testing.T) {
+	p, err := gorenogymodbus.LookupProfile("lifepo4-12v")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		overrides func(s *gorenogymodbus.Settings)
+		expected  string
+	}{
+		{
+			name: "lower float",
+			overrides: func(s *gorenogymodbus.Settings) {
+				s.FloatingChargingVoltage = decimal.NewFromFloat(13.4)
+			},
+		},
+		{
+			name: "float above boost",
+			overrides: func(s *gorenogymodbus.Settings) {
+				s.FloatingChargingVoltage = decimal.NewFromFloat(14.4)
+			},
+			expected: "invalid profile cabin: boost_charging_voltage must be >= floating_charging_voltage: 14.2, 14.4",
+		},
+		{
+			name: "discharge voltages crossed",
+			overrides: func(s *gorenogymodbus.Settings) {
+				s.OverDischargeVoltage = decimal.NewFromFloat(12.2)
+			},
+			expected: "invalid profile cabin: under_voltage_warning_level must be > over_discharge_voltage: 12, 12.2",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			derived, err := p.Derive("cabin", tc.overrides)
+			if tc.expected != "" {
+				assert.EqualError(t, err, tc.expected)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "cabin", derived.Name)
+			assert.Equal(t, "derived from lifepo4-12v", derived.Description)
+			assert.Equal(t, "13.5", p.Settings.FloatingChargingVoltage.String())
+		})
+	}
+}
+
+func TestProfileWrites(t *testing.T) {
+	p, err := gorenogymodbus.LookupProfile("agm-12v")
+	require.NoError(t, err)
+
+	current := sealedSettings()
+	writes, err := p.Writes(&current)
+	require.NoError(t, err)
+	assert.Equal(t, []gorenogymodbus.SettingsWrite{
+		{Address: 0xE003, Values: []uint16{0x0C0C}, Fields: []string{"system_voltage"}},
+		{Address: 0xE014, Values: []uint16{0x0003}, Fields: []string{"temperature_compensation_factor"}},
+	}, writes)
+
+	desired, err := p.Apply(&current)
+	require.NoError(t, err)
+	assert.Equal(t, current.NominalBatteryCapacity, desired.NominalBatteryCapacity)
+	assert.Equal(t, current.LoadWorkingMode, desired.LoadWorkingMode)
+	assert.Equal(t, current.EndOfDischargeSOC, desired.EndOfDischargeSOC)
+}
@@ -0,0 +1,243 @@
+// Package promcollector exposes controller readings as Prometheus metrics.
+package promcollector
+
+import (
+	"errors"
+	"sync"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shopspring/decimal"
+)
+
+const namespace = "renogy"
+
+type metric struct {
+	field     string // json name of the DynamicControllerInformation field
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	value     func(dci *gorenogymodbus.DynamicControllerInformation) float64
+}
+
+func newMetric(field string, name string, help string, valueType prometheus.ValueType, value func(dci *gorenogymodbus.DynamicControllerInformation) float64) metric {
+	return metric{
+		field:     field,
+		desc:      prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, nil, nil),
+		valueType: valueType,
+		value:     value,
+	}
+}
+
+func decimalValue(f func(dci *gorenogymodbus.DynamicControllerInformation) decimal.Decimal) func(dci *gorenogymodbus.DynamicControllerInformation) float64 {
+	return func(dci *gorenogymodbus.DynamicControllerInformation) float64 {
+		return f(dci).InexactFloat64()
+	}
+}
+
+func intValue(f func(dci *gorenogymodbus.DynamicControllerInformation) int) func(dci *gorenogymodbus.DynamicControllerInformation) float64 {
+	return func(dci *gorenogymodbus.DynamicControllerInformation) float64 {
+		return float64(f(dci))
+	}
+}
+
+type dci = gorenogymodbus.DynamicControllerInformation
+
+var metrics = []metric{
+	newMetric("battery_capacity_soc", "battery_capacity_soc_percent", "Battery state of charge in percent.", prometheus.GaugeValue,
+		intValue(func(d *dci) int { return d.BatteryCapacitySOC })),
+	newMetric("battery_voltage", "battery_voltage_volts", "Battery voltage.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.BatteryVoltage })),
+	newMetric("charging_current", "charging_current_amperes", "Battery charging current.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.ChargingCurrent })),
+	newMetric("controller_temperature", "controller_temperature_celsius", "Controller temperature.", prometheus.GaugeValue,
+		intValue(func(d *dci) int { return d.ControllerTemperature })),
+	newMetric("battery_temperature", "battery_temperature_celsius", "Battery temperature.", prometheus.GaugeValue,
+		intValue(func(d *dci) int { return d.BatteryTemperature })),
+	newMetric("street_light_load_voltage", "street_light_load_voltage_volts", "Load voltage.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.StreetLightLoadVoltage })),
+	newMetric("street_light_load_current", "street_light_load_current_amperes", "Load current.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.StreetLightLoadCurrent })),
+	newMetric("street_light_load_power", "street_light_load_power_watts", "Load power.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.StreetLightLoadPower })),
+	newMetric("solar_panel_voltage", "solar_panel_voltage_volts", "Solar panel voltage.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.SolarPanelVoltage })),
+	newMetric("solar_panel_current", "solar_panel_current_amperes", "Solar panel current.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.SolarPanelCurrent })),
+	newMetric("charging_power", "charging_power_watts", "Charging power.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.ChargingPower })),
+	newMetric("battery_minimum_voltage_current_day", "battery_minimum_voltage_current_day_volts", "Minimum battery voltage of the current day.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.BatteryMinimumVoltageCurrentDay })),
+	newMetric("battery_maximum_voltage_current_day", "battery_maximum_voltage_current_day_volts", "Maximum battery voltage of the current day.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.BatteryMaximumVoltageCurrentDay })),
+	newMetric("maximum_charging_current_current_day", "maximum_charging_current_current_day_amperes", "Maximum charging current of the current day.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.MaximumChargingCurrentCurrentDay })),
+	newMetric("maximum_discharging_current_current_day", "maximum_discharging_current_current_day_amperes", "Maximum discharging current of the current day.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.MaximumDischargingCurrentCurrentDay })),
+	newMetric("maximum_charging_power_current_day", "maximum_charging_power_current_day_watts", "Maximum charging power of the current day.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.MaximumChargingPowerCurrentDay })),
+	newMetric("maximum_discharging_power_current_day", "maximum_discharging_power_current_day_watts", "Maximum discharging power of the current day.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.MaximumDischargingPowerCurrentDay })),
+	newMetric("charging_amp_hours_current_day", "charging_current_day_amp_hours", "Charging amp hours of the current day.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.ChargingAmpHoursCurrentDay })),
+	newMetric("discharging_amp_hours_current_day", "discharging_current_day_amp_hours", "Discharging amp hours of the current day.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.DischargingAmpHoursCurrentDay })),
+	newMetric("power_generation_current_day", "power_generation_current_day_kilowatt_hours", "Power generation of the current day.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.PowerGenerationCurrentDay })),
+	newMetric("power_consumption_current_day", "power_consumption_current_day_kilowatt_hours", "Power consumption of the current day.", prometheus.GaugeValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.PowerConsumptionCurrentDay })),
+	newMetric("total_operating_days", "operating_days_total", "Total number of operating days.", prometheus.CounterValue,
+		intValue(func(d *dci) int { return d.TotalOperatingDays })),
+	newMetric("total_battery_over_discharges", "battery_over_discharges_total", "Total number of battery over discharges.", prometheus.CounterValue,
+		intValue(func(d *dci) int { return d.TotalBatteryOverDischarges })),
+	newMetric("total_battery_full_charges", "battery_full_charges_total", "Total number of battery full charges.", prometheus.CounterValue,
+		intValue(func(d *dci) int { return d.TotalBatteryFullCharges })),
+	newMetric("total_charging_amp_hours", "charging_amp_hours_total", "Total charging amp hours.", prometheus.CounterValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.TotalChargingAmpHours })),
+	newMetric("total_discharging_amp_hours", "discharging_amp_hours_total", "Total discharging amp hours.", prometheus.CounterValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.TotalDischargingAmpHours })),
+	newMetric("cumulative_power_generation", "power_generation_kilowatt_hours_total", "Cumulative power generation.", prometheus.CounterValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.CumulativePowerGeneration })),
+	newMetric("cumulative_power_consumption", "power_consumption_kilowatt_hours_total", "Cumulative power consumption.", prometheus.CounterValue,
+		decimalValue(func(d *dci) decimal.Decimal { return d.CumulativePowerConsumption })),
+	newMetric("street_light_status", "street_light_on", "Whether the load is switched on.", prometheus.GaugeValue,
+		func(d *dci) float64 {
+			if d.StreetLightStatus {
+				return 1
+			}
+			return 0
+		}),
+	newMetric("street_light_brightness", "street_light_brightness_percent", "Load brightness in percent.", prometheus.GaugeValue,
+		intValue(func(d *dci) int { return d.StreetLightBrightness })),
+}
+
+var (
+	chargingStateDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "charging_state"),
+		"Charging state, 1 for the current state and 0 otherwise.", []string{"state"}, nil)
+	controllerFaultDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "controller_fault"),
+		"Controller fault, 1 when active and 0 otherwise.", []string{"fault"}, nil)
+	upDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "up"),
+		"Whether the last reading of the controller succeeded.", nil, nil)
+
+	chargingStates = []gorenogymodbus.ChargingState{
+		gorenogymodbus.ChargingDeactivated,
+		gorenogymodbus.ChargingActivated,
+		gorenogymodbus.MPPTChargingMode,
+		gorenogymodbus.EqualizingChargingMode,
+		gorenogymodbus.BoostChargingMode,
+		gorenogymodbus.FloatingChargingMode,
+		gorenogymodbus.CurrentLimitingOverPower,
+	}
+)
+
+// Collector is a prometheus.Collector for controller readings. It either reads the
+// controller on every scrape or serves the reading last passed to Set.
+type Collector struct {
+	mu           sync.Mutex
+	client       *gorenogymodbus.ModbusClient
+	cached       *gorenogymodbus.DynamicControllerInformation
+	scrapeErrors prometheus.Counter
+}
+
+// NewCollector creates a Collector that reads the controller on every scrape.
+func NewCollector(client *gorenogymodbus.ModbusClient) *Collector {
+	c := NewCachedCollector()
+	c.client = client
+	return c
+}
+
+// NewCachedCollector creates a Collector that serves the reading last passed to Set,
+// for when the controller is already polled elsewhere.
+func NewCachedCollector() *Collector {
+	return &Collector{
+		scrapeErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "scrape_errors_total",
+			Help:      "Total number of failed controller reads.",
+		}),
+	}
+}
+
+// Set replaces the cached reading.
+func (c *Collector) Set(dci *gorenogymodbus.DynamicControllerInformation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cached = dci
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range metrics {
+		ch <- m.desc
+	}
+	ch <- chargingStateDesc
+	ch <- controllerFaultDesc
+	ch <- upDesc
+	c.scrapeErrors.Describe(ch)
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dci, err := c.reading()
+	if err != nil {
+		// a cached collector waiting for its first reading has not failed a read
+		if !errors.Is(err, errNoReading) {
+			c.scrapeErrors.Inc()
+		}
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0)
+		c.scrapeErrors.Collect(ch)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1)
+	c.scrapeErrors.Collect(ch)
+
+	for _, m := range metrics {
+		if !dci.Has(m.field) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, m.value(dci))
+	}
+
+	if dci.Has("charging_state") {
+		for _, cs := range chargingStates {
+			ch <- prometheus.MustNewConstMetric(chargingStateDesc, prometheus.GaugeValue, boolValue(cs.String() == dci.ChargingState), cs.String())
+		}
+	}
+
+	if dci.Has("controller_faults") {
+		active := map[string]bool{}
+		for _, f := range dci.ControllerFaults {
+			active[f] = true
+		}
+		for _, f := range gorenogymodbus.ControllerFaultsMap {
+			ch <- prometheus.MustNewConstMetric(controllerFaultDesc, prometheus.GaugeValue, boolValue(active[f.String()]), f.String())
+		}
+	}
+}
+
+var errNoReading = errors.New("no reading available")
+
+func (c *Collector) reading() (*gorenogymodbus.DynamicControllerInformation, error) {
+	if c.client == nil {
+		if c.cached == nil {
+			return nil, errNoReading
+		}
+		return c.cached, nil
+	}
+
+	data, err := c.client.ReadData()
+	if err != nil {
+		return nil, err
+	}
+
+	return gorenogymodbus.Parse(data)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
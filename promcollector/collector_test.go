@@ -0,0 +1,112 @@
+package promcollector_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/goburrow/modbus"
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/promcollector"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClient struct {
+	modbus.Client
+	data []byte
+	err  error
+}
+
+func (fc *fakeClient) ReadHoldingRegisters(address uint16, quantity uint16) ([]byte, error) {
+	return fc.data, fc.err
+}
+
+func TestCachedCollector(t *testing.T) {
+	c := promcollector.NewCachedCollector()
+	c.Set(&gorenogymodbus.DynamicControllerInformation{
+		BatteryCapacitySOC: 80,
+		BatteryVoltage:     decimal.NewFromFloat(13.2),
+		ChargingState:      gorenogymodbus.FloatingChargingMode.String(),
+		ControllerFaults:   []string{gorenogymodbus.BatteryUnderVoltage.String()},
+	})
+
+	expected := `
+# HELP renogy_battery_voltage_volts Battery voltage.
+# TYPE renogy_battery_voltage_volts gauge
+renogy_battery_voltage_volts 13.2
+# HELP renogy_charging_state Charging state, 1 for the current state and 0 otherwise.
+# TYPE renogy_charging_state gauge
+renogy_charging_state{state="boost charging mode"} 0
+renogy_charging_state{state="charging activated"} 0
+renogy_charging_state{state="charging deactivated"} 0
+renogy_charging_state{state="current limiting overpower"} 0
+renogy_charging_state{state="equalizing charging mode"} 0
+renogy_charging_state{state="floating charging mode"} 1
+renogy_charging_state{state="mppt charging mode"} 0
+# HELP renogy_up Whether the last reading of the controller succeeded.
+# TYPE renogy_up gauge
+renogy_up 1
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"renogy_battery_voltage_volts", "renogy_charging_state", "renogy_up")
+	assert.NoError(t, err)
+
+	expected = `
+# HELP renogy_controller_fault Controller fault, 1 when active and 0 otherwise.
+# TYPE renogy_controller_fault gauge
+renogy_controller_fault{fault="ambient temperature too high"} 0
+renogy_controller_fault{fault="anti reverse mos short"} 0
+renogy_controller_fault{fault="battery over discharge"} 0
+renogy_controller_fault{fault="battery over voltage"} 0
+renogy_controller_fault{fault="battery under voltage"} 1
+renogy_controller_fault{fault="charge mos short circuit"} 0
+renogy_controller_fault{fault="controller temperature too high"} 0
+renogy_controller_fault{fault="load over power or load over current"} 0
+renogy_controller_fault{fault="load short circuit"} 0
+renogy_controller_fault{fault="photovoltaic input overpower"} 0
+renogy_controller_fault{fault="photovoltaic input side over voltage"} 0
+renogy_controller_fault{fault="photovoltaic input side short circuit"} 0
+renogy_controller_fault{fault="solar panel counter current"} 0
+renogy_controller_fault{fault="solar panel reversely connected"} 0
+renogy_controller_fault{fault="solar panel working point overvoltage"} 0
+`
+	err = testutil.CollectAndCompare(c, strings.NewReader(expected), "renogy_controller_fault")
+	assert.NoError(t, err)
+}
+
+func TestCachedCollectorBeforeFirstReading(t *testing.T) {
+	c := promcollector.NewCachedCollector()
+
+	assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP renogy_scrape_errors_total Total number of failed controller reads.
+# TYPE renogy_scrape_errors_total counter
+renogy_scrape_errors_total 0
+# HELP renogy_up Whether the last reading of the controller succeeded.
+# TYPE renogy_up gauge
+renogy_up 0
+`), "renogy_scrape_errors_total", "renogy_up"))
+}
+
+func TestPollingCollector(t *testing.T) {
+	fc := &fakeClient{data: make([]byte, 70)}
+	fc.data[1] = 0x64
+	c := promcollector.NewCollector(&gorenogymodbus.ModbusClient{Client: fc})
+
+	assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP renogy_battery_capacity_soc_percent Battery state of charge in percent.
+# TYPE renogy_battery_capacity_soc_percent gauge
+renogy_battery_capacity_soc_percent 100
+`), "renogy_battery_capacity_soc_percent"))
+
+	fc.err = fmt.Errorf("timeout")
+	assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP renogy_scrape_errors_total Total number of failed controller reads.
+# TYPE renogy_scrape_errors_total counter
+renogy_scrape_errors_total 1
+# HELP renogy_up Whether the last reading of the controller succeeded.
+# TYPE renogy_up gauge
+renogy_up 0
+`), "renogy_scrape_errors_total", "renogy_up"))
+}
@@ -0,0 +1,281 @@
+// Package reconcile brings the settings of a controller to a desired state declared
+// in a document, writing only the registers that differ and verifying them.
+package reconcile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/backup"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the version of the desired state document format.
+const Version = 1
+
+// Document declares the desired settings of a device. Only the settings listed, by
+// their json names, and those of the profile are managed, everything else is left
+// as it is. Settings override the profile.
+type Document struct {
+	Version int `json:"version" yaml:"version"`
+	// Device is the serial number the document is meant for, checked when set.
+	Device string `json:"device,omitempty" yaml:"device,omitempty"`
+	// Profile is the name of a preset profile, see gorenogymodbus.Profiles.
+	Profile  string                 `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Settings map[string]interface{} `json:"settings" yaml:"settings"`
+}
+
+// Decode reads a document, YAML or JSON like backup files.
+func Decode(r io.Reader, format backup.Format) (*Document, error) {
+	var doc Document
+	switch format {
+	case backup.JSON:
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode document: %w", err)
+		}
+	default:
+		dec := yaml.NewDecoder(r)
+		dec.KnownFields(true)
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode document: %w", err)
+		}
+	}
+
+	if doc.Version == 0 {
+		return nil, fmt.Errorf("document has no version")
+	}
+	if doc.Version > Version {
+		return nil, fmt.Errorf("unsupported document version: %d", doc.Version)
+	}
+
+	return &doc, nil
+}
+
+// Desired returns the current settings with the managed settings of the document
+// applied.
+func (d *Document) Desired(current *gorenogymodbus.Settings) (*gorenogymodbus.Settings, error) {
+	if _, ok := d.Settings["recognized_voltage"]; ok {
+		return nil, fmt.Errorf("recognized_voltage is read only")
+	}
+
+	if d.Profile != "" {
+		p, err := gorenogymodbus.LookupProfile(d.Profile)
+		if err != nil {
+			return nil, err
+		}
+		current = p.Merge(current)
+	}
+
+	b, err := json.Marshal(current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal current settings: %w", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal current settings: %w", err)
+	}
+	for name, value := range d.Settings {
+		if _, ok := fields[name]; !ok {
+			return nil, fmt.Errorf("unknown setting: %s", name)
+		}
+		fields[name] = value
+	}
+
+	b, err = json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal desired settings: %w", err)
+	}
+	var desired gorenogymodbus.Settings
+	if err := json.Unmarshal(b, &desired); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal desired settings: %w", err)
+	}
+
+	return &desired, nil
+}
+
+type Status string
+
+const (
+	// StatusCompliant is a setting that already had its desired value.
+	StatusCompliant Status = "compliant"
+	// StatusChanged is a setting that was written and read back with its desired value.
+	StatusChanged Status = "changed"
+	// StatusPending is a setting a dry run would have written.
+	StatusPending Status = "pending"
+	// StatusFailed is a setting that could not be written or read back differently.
+	StatusFailed Status = "failed"
+)
+
+// FieldResult is the outcome for a managed setting.
+type FieldResult struct {
+	Field   string `json:"field"`
+	Status  Status `json:"status"`
+	Before  string `json:"before"`
+	Desired string `json:"desired"`
+	After   string `json:"after"`
+	Error   string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Device string                         `json:"device"`
+	DryRun bool                           `json:"dry_run"`
+	Fields []FieldResult                  `json:"fields"`
+	Writes []gorenogymodbus.SettingsWrite `json:"writes"`
+}
+
+// Failed reports whether any managed setting failed.
+func (r *Report) Failed() bool {
+	for _, f := range r.Fields {
+		if f.Status == StatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of managed settings with a status.
+func (r *Report) Count(status Status) int {
+	var n int
+	for _, f := range r.Fields {
+		if f.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Reconcile reads the settings of a controller, writes the registers of managed
+// settings that differ from the document and reads them back to verify. Running it
+// again on a reconciled device writes nothing. An error is returned when the device
+// could not be reconciled at all, failures of single writes are in the report.
+func Reconcile(mc *gorenogymodbus.ModbusClient, doc *Document, dryRun bool) (*Report, error) {
+	pi, err := mc.ReadProductInformation()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read product information: %w", err)
+	}
+	if doc.Device != "" && doc.Device != pi.SerialNumber {
+		return nil, fmt.Errorf("document is for device %s, not %s", doc.Device, pi.SerialNumber)
+	}
+
+	before, err := mc.ReadSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+
+	desired, err := doc.Desired(before)
+	if err != nil {
+		return nil, err
+	}
+	if err := desired.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid desired settings: %w", err)
+	}
+	desired, err = registerValues(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	writes, err := gorenogymodbus.PlanSettingsWrites(before, desired)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Device: pi.SerialNumber, DryRun: dryRun, Writes: writes}
+	if dryRun || len(writes) == 0 {
+		report.Fields = results(doc, before, desired, before, true, nil)
+		return report, nil
+	}
+
+	writeErrors := map[string]error{}
+	for _, w := range writes {
+		if err := mc.WriteSettings([]gorenogymodbus.SettingsWrite{w}); err != nil {
+			for _, field := range w.Fields {
+				writeErrors[field] = err
+			}
+		}
+	}
+
+	after, err := mc.ReadSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read back settings: %w", err)
+	}
+	report.Fields = results(doc, before, desired, after, false, writeErrors)
+
+	return report, nil
+}
+
+// results compares the managed settings in the order of the Settings fields.
+func results(doc *Document, before, desired, after *gorenogymodbus.Settings, pending bool, writeErrors map[string]error) []FieldResult {
+	b, d, a := fieldValues(before), fieldValues(desired), fieldValues(after)
+
+	managed := map[string]bool{}
+	for name := range doc.Settings {
+		managed[name] = true
+	}
+	if doc.Profile != "" {
+		for _, name := range gorenogymodbus.ProfileFields {
+			managed[name] = true
+		}
+	}
+
+	var fields []FieldResult
+	for _, name := range fieldNames() {
+		if !managed[name] {
+			continue
+		}
+
+		r := FieldResult{Field: name, Before: b[name], Desired: d[name], After: a[name]}
+		// the read back value decides, a write can fail after the device applied it
+		switch {
+		case r.Before == r.Desired:
+			r.Status = StatusCompliant
+		case pending:
+			r.Status = StatusPending
+		case r.After == r.Desired:
+			r.Status = StatusChanged
+		case writeErrors[name] != nil:
+			r.Status = StatusFailed
+			r.Error = writeErrors[name].Error()
+		default:
+			r.Status = StatusFailed
+			r.Error = "read back value differs"
+		}
+		fields = append(fields, r)
+	}
+
+	return fields
+}
+
+func fieldNames() []string {
+	t := reflect.TypeOf(gorenogymodbus.Settings{})
+	names := make([]string, t.NumField())
+	for i := range names {
+		names[i], _, _ = strings.Cut(t.Field(i).Tag.Get("json"), ",")
+	}
+	return names
+}
+
+func fieldValues(s *gorenogymodbus.Settings) map[string]string {
+	v := reflect.ValueOf(*s)
+	values := map[string]string{}
+	for i, name := range fieldNames() {
+		values[name] = fmt.Sprint(v.Field(i).Interface())
+	}
+	return values
+}
+
+// registerValues returns the settings as they read back once written, voltages
+// being kept in steps of 0.1 V. Comparing with them keeps a desired 14.44 V from
+// failing, or staying pending, as 14.4 V.
+func registerValues(s *gorenogymodbus.Settings) (*gorenogymodbus.Settings, error) {
+	b, err := s.Synthesize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode desired settings: %w", err)
+	}
+	return gorenogymodbus.ParseSettings(b)
+}
@@ -0,0 +1,211 @@
+package reconcile_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/backup"
+	"github.com/michaelpeterswa/go-renogy-modbus/reconcile"
+	"github.com/michaelpeterswa/go-renogy-modbus/simulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const document = `version: 1
+device: "20230810"
+settings:
+  battery_type: user defined
+  boost_charging_voltage: 14.2
+  floating_charging_voltage: 13.8
+  load_working_mode: 17
+`
+
+func decode(t *testing.T, s string) *reconcile.Document {
+	doc, err := reconcile.Decode(strings.NewReader(s), backup.YAML)
+	require.NoError(t, err)
+	return doc
+}
+
+// stubborn is a device that refuses writes of the battery type and acknowledges
+// writes of the load working mode but ignores them.
+type stubborn struct {
+	*simulator.Model
+}
+
+func (s stubborn) WriteRegisters(address uint16, values []byte) error {
+	switch address {
+	case 0xE004:
+		return simulator.ExceptionServerDeviceFailure
+	case 0xE01D:
+		return nil
+	default:
+		return s.Model.WriteRegisters(address, values)
+	}
+}
+
+func newServer() *simulator.Server {
+	now := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	return simulator.NewServer(1, simulator.NewModel(simulator.ModelConfig{Clock: func() time.Time { return now }}))
+}
+
+func TestReconcile(t *testing.T) {
+	s := newServer()
+	mc := &gorenogymodbus.ModbusClient{Client: s.Client()}
+	doc := decode(t, document)
+
+	report, err := reconcile.Reconcile(mc, doc, true)
+	require.NoError(t, err)
+	assert.Equal(t, []reconcile.FieldResult{
+		{Field: "battery_type", Status: reconcile.StatusPending, Before: "sealed", Desired: "user defined", After: "sealed"},
+		{Field: "boost_charging_voltage", Status: reconcile.StatusPending, Before: "14.4", Desired: "14.2", After: "14.4"},
+		{Field: "floating_charging_voltage", Status: reconcile.StatusCompliant, Before: "13.8", Desired: "13.8", After: "13.8"},
+		{Field: "load_working_mode", Status: reconcile.StatusPending, Before: "15", Desired: "17", After: "15"},
+	}, report.Fields)
+	assert.Len(t, report.Writes, 3)
+
+	report, err = reconcile.Reconcile(mc, doc, false)
+	require.NoError(t, err)
+	assert.False(t, report.Failed())
+	assert.Equal(t, 3, report.Count(reconcile.StatusChanged))
+	assert.Equal(t, 1, report.Count(reconcile.StatusCompliant))
+
+	// idempotent
+	report, err = reconcile.Reconcile(mc, doc, false)
+	require.NoError(t, err)
+	assert.Empty(t, report.Writes)
+	assert.Equal(t, 4, report.Count(reconcile.StatusCompliant))
+}
+
+func TestReconcileRegisterResolution(t *testing.T) {
+	mc := &gorenogymodbus.ModbusClient{Client: newServer().Client()}
+
+	// 14.44 V is kept as 14.4 V, the boost charging voltage already set
+	report, err := reconcile.Reconcile(mc, decode(t, "version: 1\nsettings:\n  boost_charging_voltage: 14.44\n"), false)
+	require.NoError(t, err)
+	assert.Empty(t, report.Writes)
+	assert.Equal(t, []reconcile.FieldResult{
+		{Field: "boost_charging_voltage", Status: reconcile.StatusCompliant, Before: "14.4", Desired: "14.4", After: "14.4"},
+	}, report.Fields)
+
+	doc := decode(t, "version: 1\nsettings:\n  boost_charging_voltage: 14.56\n")
+	report, err = reconcile.Reconcile(mc, doc, false)
+	require.NoError(t, err)
+	assert.Equal(t, []reconcile.FieldResult{
+		{Field: "boost_charging_voltage", Status: reconcile.StatusChanged, Before: "14.4", Desired: "14.6", After: "14.6"},
+	}, report.Fields)
+
+	report, err = reconcile.Reconcile(mc, doc, false)
+	require.NoError(t, err)
+	assert.Empty(t, report.Writes)
+	assert.Equal(t, 1, report.Count(reconcile.StatusCompliant))
+}
+
+func TestReconcileFailures(t *testing.T) {
+	s := newServer()
+	s.Device = stubborn{s.Device.(*simulator.Model)}
+	// the response to the boost charging voltage write is lost after it was applied
+	s.Injector = simulator.NewInjector(1)
+	s.Injector.Add(simulator.Rule{
+		Injection: simulator.DropResponse,
+		Match:     func(function byte, address uint16) bool { return address == 0xE008 },
+	})
+	mc := &gorenogymodbus.ModbusClient{Client: s.Client()}
+
+	report, err := reconcile.Reconcile(mc, decode(t, document), false)
+	require.NoError(t, err)
+	assert.True(t, report.Failed())
+	assert.Equal(t, []reconcile.FieldResult{
+		{
+			Field: "battery_type", Status: reconcile.StatusFailed, Before: "sealed", Desired: "user defined", After: "sealed",
+			Error: "failed to write settings registers 0xE004 = 0x0000 (battery_type): modbus: exception '4' (server device failure), function '134'",
+		},
+		{Field: "boost_charging_voltage", Status: reconcile.StatusChanged, Before: "14.4", Desired: "14.2", After: "14.2"},
+		{Field: "floating_charging_voltage", Status: reconcile.StatusCompliant, Before: "13.8", Desired: "13.8", After: "13.8"},
+		{Field: "load_working_mode", Status: reconcile.StatusFailed, Before: "15", Desired: "17", After: "15", Error: "read back value differs"},
+	}, report.Fields)
+}
+
+func TestReconcileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		expected string
+	}{
+		{
+			name:     "other device",
+			document: "version: 1\ndevice: \"1234\"\nsettings: {}\n",
+			expected: "document is for device 1234, not 20230810",
+		},
+		{
+			name:     "unknown setting",
+			document: "version: 1\nsettings:\n  battery_chemistry: gel\n",
+			expected: "unknown setting: battery_chemistry",
+		},
+		{
+			name:     "read only setting",
+			document: "version: 1\nsettings:\n  recognized_voltage: 24\n",
+			expected: "recognized_voltage is read only",
+		},
+		{
+			name:     "invalid",
+			document: "version: 1\nsettings:\n  floating_charging_voltage: 14.5\n",
+			expected: "invalid desired settings: boost_charging_voltage must be >= floating_charging_voltage: 14.4, 14.5",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mc := &gorenogymodbus.ModbusClient{Client: newServer().Client()}
+			_, err := reconcile.Reconcile(mc, decode(t, tc.document), false)
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	_, err := reconcile.Decode(strings.NewReader(`{"settings": {}}`), backup.JSON)
+	assert.EqualError(t, err, "document has no version")
+
+	_, err = reconcile.Decode(strings.NewReader("version: 2\n"), backup.YAML)
+	assert.EqualError(t, err, "unsupported document version: 2")
+}
+
+func TestReconcileProfile(t *testing.T) {
+	mc := &gorenogymodbus.ModbusClient{Client: newServer().Client()}
+	doc := decode(t, "version: 1\nprofile: lifepo4-12v\nsettings:\n  floating_charging_voltage: 13.4\n")
+
+	report, err := reconcile.Reconcile(mc, doc, false)
+	require.NoError(t, err)
+	assert.False(t, report.Failed())
+	assert.Len(t, report.Fields, len(gorenogymodbus.ProfileFields))
+
+	settings, err := mc.ReadSettings()
+	require.NoError(t, err)
+	assert.Equal(t, gorenogymodbus.UserDefinedBattery.String(), settings.BatteryType)
+	assert.Equal(t, "13.4", settings.FloatingChargingVoltage.String())
+	assert.Equal(t, "14.2", settings.BoostChargingVoltage.String())
+
+	report, err = reconcile.Reconcile(mc, doc, false)
+	require.NoError(t, err)
+	assert.Empty(t, report.Writes)
+
+	_, err = reconcile.Reconcile(mc, decode(t, "version: 1\nprofile: lead-12v\n"), false)
+	assert.EqualError(t, err, "unknown profile: lead-12v")
+}
+
+func TestDesiredProfile(t *testing.T) {
+	mc := &gorenogymodbus.ModbusClient{Client: newServer().Client()}
+	current, err := mc.ReadSettings()
+	require.NoError(t, err)
+	current.NominalBatteryCapacity = 0
+
+	// the settings of the document apply over the profile before anything is validated
+	doc := decode(t, "version: 1\nprofile: lifepo4-12v\nsettings:\n  nominal_battery_capacity: 100\n")
+	desired, err := doc.Desired(current)
+	require.NoError(t, err)
+	assert.Equal(t, 100, desired.NominalBatteryCapacity)
+	assert.Equal(t, gorenogymodbus.UserDefinedBattery.String(), desired.BatteryType)
+	require.NoError(t, desired.Validate())
+}
@@ -16,16 +16,87 @@ type ModbusClient struct {
 }
 
 func NewModbusClient(logger *log.Logger, address string, idleTimeout time.Duration) (*ModbusClient, error) {
-	// Modbus RTU/ASCII
-	handler := modbus.NewRTUClientHandler(address)
-	handler.BaudRate = 9600
-	handler.SlaveId = 1
-	handler.Timeout = 1 * time.Second
-	handler.IdleTimeout = idleTimeout
-	handler.StopBits = 1
-	handler.DataBits = 8
-	handler.Parity = "N"
-	handler.Logger = logger
+	return NewModbusClientWithConfig(ClientConfig{
+		Address:     address,
+		IdleTimeout: idleTimeout,
+		Logger:      logger,
+	})
+}
+
+// Transport is how Modbus frames reach the controller.
+type Transport string
+
+const (
+	TransportRTU   Transport = "rtu"
+	TransportASCII Transport = "ascii"
+	TransportTCP   Transport = "tcp"
+)
+
+// ClientConfig configures a ModbusClient. Zero values default to what the Rover
+// ships with: RTU at 9600 baud 8N1 and slave ID 1.
+type ClientConfig struct {
+	Address     string // serial device, or host:port for TransportTCP
+	Transport   Transport
+	BaudRate    int
+	SlaveID     byte
+	Timeout     time.Duration
+	IdleTimeout time.Duration
+	Logger      *log.Logger
+}
+
+func NewModbusClientWithConfig(config ClientConfig) (*ModbusClient, error) {
+	if config.Transport == "" {
+		config.Transport = TransportRTU
+	}
+	if config.BaudRate == 0 {
+		config.BaudRate = 9600
+	}
+	if config.SlaveID == 0 {
+		config.SlaveID = 1
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 1 * time.Second
+	}
+
+	var handler interface {
+		modbus.ClientHandler
+		Connect() error
+		Close() error
+	}
+	switch config.Transport {
+	case TransportRTU:
+		// Modbus RTU/ASCII
+		h := modbus.NewRTUClientHandler(config.Address)
+		h.BaudRate = config.BaudRate
+		h.SlaveId = config.SlaveID
+		h.Timeout = config.Timeout
+		h.IdleTimeout = config.IdleTimeout
+		h.StopBits = 1
+		h.DataBits = 8
+		h.Parity = "N"
+		h.Logger = config.Logger
+		handler = h
+	case TransportASCII:
+		h := modbus.NewASCIIClientHandler(config.Address)
+		h.BaudRate = config.BaudRate
+		h.SlaveId = config.SlaveID
+		h.Timeout = config.Timeout
+		h.IdleTimeout = config.IdleTimeout
+		h.StopBits = 1
+		h.DataBits = 8
+		h.Parity = "N"
+		h.Logger = config.Logger
+		handler = h
+	case TransportTCP:
+		h := modbus.NewTCPClientHandler(config.Address)
+		h.SlaveId = config.SlaveID
+		h.Timeout = config.Timeout
+		h.IdleTimeout = config.IdleTimeout
+		h.Logger = config.Logger
+		handler = h
+	default:
+		return nil, fmt.Errorf("invalid transport: %s", config.Transport)
+	}
 
 	err := handler.Connect()
 	if err != nil {
@@ -38,12 +109,12 @@ func NewModbusClient(logger *log.Logger, address string, idleTimeout time.Durati
 	return &ModbusClient{client}, nil
 }
 
-func (mc *ModbusClient) ReadData() ([]byte, error) {
-	var (
-		dataStartAddress uint16 = 0x100
-		dataQuantity     uint16 = 35
-	)
+const (
+	dataStartAddress uint16 = 0x100
+	dataQuantity     uint16 = 35
+)
 
+func (mc *ModbusClient) ReadData() ([]byte, error) {
 	res, err := mc.readHoldingRegisters(dataStartAddress, dataQuantity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read holding registers: %w", err)
@@ -52,6 +123,26 @@ func (mc *ModbusClient) ReadData() ([]byte, error) {
 	return res, nil
 }
 
+const (
+	loadCommandAddress uint16 = 0x10A
+)
+
+// SetLoad switches the load (street light) on or off with the write only light
+// on/off command register (0x10A).
+func (mc *ModbusClient) SetLoad(on bool) error {
+	var value uint16
+	if on {
+		value = 1
+	}
+
+	_, err := mc.Client.WriteSingleRegister(loadCommandAddress, value)
+	if err != nil {
+		return fmt.Errorf("failed to write load command register: %w", err)
+	}
+
+	return nil
+}
+
 func (mc *ModbusClient) readHoldingRegisters(address uint16, quantity uint16) (results []byte, err error) {
 	res, err := mc.Client.ReadHoldingRegisters(address, quantity)
 	if err != nil {
@@ -279,6 +370,11 @@ type DynamicControllerInformation struct {
 	StreetLightBrightness               int             `json:"street_light_brightness"`                 // 0x120 (eight higher bits)
 	ChargingState                       string          `json:"charging_state"`                          // 0x120 (eight lower bits)
 	ControllerFaults                    []string        `json:"controller_faults"`                       // 0x121-122
+
+	// partial, start and end record the register range decoded by ParseRange.
+	// The zero value means the whole block was decoded.
+	partial    bool
+	start, end uint16
 }
 
 func Parse(dataBytes []byte) (*DynamicControllerInformation, error) {
@@ -405,7 +501,7 @@ func (dci *DynamicControllerInformation) Synthesize() ([]byte, error) {
 	data = binary.BigEndian.AppendUint16(data, uint16(dci.DischargingAmpHoursCurrentDay.InexactFloat64()))
 
 	data = binary.BigEndian.AppendUint16(data, uint16(dci.PowerGenerationCurrentDay.Mul(decimal.NewFromInt(10000)).InexactFloat64()))
-	data = binary.BigEndian.AppendUint16(data, uint16(dci.PowerGenerationCurrentDay.Mul(decimal.NewFromInt(10000)).InexactFloat64()))
+	data = binary.BigEndian.AppendUint16(data, uint16(dci.PowerConsumptionCurrentDay.Mul(decimal.NewFromInt(10000)).InexactFloat64()))
 	data = binary.BigEndian.AppendUint16(data, uint16(dci.TotalOperatingDays))
 	data = binary.BigEndian.AppendUint16(data, uint16(dci.TotalBatteryOverDischarges))
 	data = binary.BigEndian.AppendUint16(data, uint16(dci.TotalBatteryFullCharges))
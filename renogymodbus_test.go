@@ -1,11 +1,15 @@
 package gorenogymodbus_test
 
 import (
+	"encoding/binary"
+	"io"
+	"net"
 	"testing"
 
 	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSynthesize(t *testing.T) {
@@ -59,7 +63,7 @@ func TestSynthesize(t *testing.T) {
 				0x84, 0x00, 0x96, 0x01, 0x90,
 				0x00, 0x13, 0x00, 0x0c, 0x00,
 				0x04, 0x00, 0x04, 0x75, 0x30,
-				0x75, 0x30, 0x00, 0x0c, 0x00,
+				0x27, 0x10, 0x00, 0x0c, 0x00,
 				0x00, 0x00, 0x0a, 0x00, 0x00,
 				0x00, 0x0a, 0x00, 0x00, 0x00,
 				0x0a, 0x00, 0x01, 0x86, 0xa0,
@@ -77,3 +81,45 @@ func TestSynthesize(t *testing.T) {
 		})
 	}
 }
+
+func TestNewModbusClientWithConfig(t *testing.T) {
+	_, err := gorenogymodbus.NewModbusClientWithConfig(gorenogymodbus.ClientConfig{Transport: "carrier pigeon"})
+	assert.EqualError(t, err, "invalid transport: carrier pigeon")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	// the client connects once to check the address and again on the first request
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			// MBAP header and a read holding registers request for one register
+			request := make([]byte, 12)
+			if _, err := io.ReadFull(conn, request); err != nil {
+				conn.Close()
+				continue
+			}
+			response := append([]byte(nil), request[:4]...)
+			response = binary.BigEndian.AppendUint16(response, 5)
+			response = append(response, request[6], 0x03, 0x02, 0x00, 0x64)
+			conn.Write(response) //nolint:errcheck
+			conn.Close()
+		}
+	}()
+
+	mc, err := gorenogymodbus.NewModbusClientWithConfig(gorenogymodbus.ClientConfig{
+		Address:   l.Addr().String(),
+		Transport: gorenogymodbus.TransportTCP,
+		SlaveID:   2,
+	})
+	require.NoError(t, err)
+
+	res, err := mc.ReadDataRange(0x100, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x64}, res)
+}
@@ -0,0 +1,195 @@
+// Package replay implements a modbus.Client that answers reads from recorded
+// registers, so ModbusClient and everything built on it can run without hardware.
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/goburrow/modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/capture"
+)
+
+// ErrExhausted is returned when no remaining record answers a read.
+var ErrExhausted = errors.New("replay exhausted")
+
+// Write is a register write received by the Client.
+type Write struct {
+	Address uint16
+	Values  []byte
+}
+
+// Client answers ReadHoldingRegisters from records in order. Each read is served by
+// the next record whose registers cover the requested range, so a script of
+// records is replayed once from start to end.
+type Client struct {
+	// Realtime delays each answer so records are served with the time between them
+	// that they were captured with.
+	Realtime bool
+	// Loop restarts from the first record when the records are exhausted.
+	Loop bool
+	// SlaveID only replays records of this slave id when not zero.
+	SlaveID byte
+
+	mu      sync.Mutex
+	records []capture.Record
+	pos     int
+	last    time.Time // timestamp of the last served record
+	served  time.Time // wall clock time it was served at
+	writes  []Write
+}
+
+// New creates a Client replaying records, e.g. a script built in memory.
+func New(records []capture.Record) *Client {
+	return &Client{records: records}
+}
+
+// Open creates a Client replaying the records of a capture file. Records failing
+// their checksum are skipped.
+func Open(r io.Reader) (*Client, error) {
+	cr, err := capture.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []capture.Record
+	for {
+		record, err := cr.Next()
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, capture.ErrChecksum) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read capture: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	return New(records), nil
+}
+
+func (c *Client) ReadHoldingRegisters(address, quantity uint16) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, err := c.next(address, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Realtime && !c.last.IsZero() && r.Timestamp.After(c.last) {
+		wait := r.Timestamp.Sub(c.last) - time.Since(c.served)
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+	c.last = r.Timestamp
+	c.served = time.Now()
+
+	offset := int(address-r.Address) * 2
+	data := make([]byte, int(quantity)*2)
+	copy(data, r.Data[offset:])
+
+	return data, nil
+}
+
+func (c *Client) next(address, quantity uint16) (capture.Record, error) {
+	for pass := 0; pass < 2; pass++ {
+		start := c.pos
+		if pass == 1 {
+			start = 0
+		}
+		for i := start; i < len(c.records); i++ {
+			r := c.records[i]
+			if c.SlaveID != 0 && r.SlaveID != c.SlaveID {
+				continue
+			}
+			if address < r.Address || int(address)+int(quantity) > int(r.Address)+int(r.Quantity()) {
+				continue
+			}
+
+			if pass == 1 {
+				c.last = time.Time{}
+			}
+			c.pos = i + 1
+			return r, nil
+		}
+
+		// a read no record answers leaves the position as it was
+		if !c.Loop {
+			break
+		}
+	}
+
+	return capture.Record{}, fmt.Errorf("no record for registers 0x%X-0x%X: %w", address, int(address)+int(quantity)-1, ErrExhausted)
+}
+
+// Writes returns the register writes received so far.
+func (c *Client) Writes() []Write {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Write{}, c.writes...)
+}
+
+func (c *Client) WriteSingleRegister(address, value uint16) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v := []byte{byte(value >> 8), byte(value)}
+	c.writes = append(c.writes, Write{Address: address, Values: v})
+
+	return v, nil
+}
+
+func (c *Client) WriteMultipleRegisters(address, quantity uint16, value []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writes = append(c.writes, Write{Address: address, Values: append([]byte{}, value...)})
+
+	return []byte{byte(quantity >> 8), byte(quantity)}, nil
+}
+
+func (c *Client) ReadCoils(address, quantity uint16) ([]byte, error) {
+	return nil, unsupported("read coils")
+}
+
+func (c *Client) ReadDiscreteInputs(address, quantity uint16) ([]byte, error) {
+	return nil, unsupported("read discrete inputs")
+}
+
+func (c *Client) WriteSingleCoil(address, value uint16) ([]byte, error) {
+	return nil, unsupported("write single coil")
+}
+
+func (c *Client) WriteMultipleCoils(address, quantity uint16, value []byte) ([]byte, error) {
+	return nil, unsupported("write multiple coils")
+}
+
+func (c *Client) ReadInputRegisters(address, quantity uint16) ([]byte, error) {
+	return nil, unsupported("read input registers")
+}
+
+func (c *Client) ReadWriteMultipleRegisters(readAddress, readQuantity, writeAddress, writeQuantity uint16, value []byte) ([]byte, error) {
+	return nil, unsupported("read write multiple registers")
+}
+
+func (c *Client) MaskWriteRegister(address, andMask, orMask uint16) ([]byte, error) {
+	return nil, unsupported("mask write register")
+}
+
+func (c *Client) ReadFIFOQueue(address uint16) ([]byte, error) {
+	return nil, unsupported("read fifo queue")
+}
+
+func unsupported(function string) error {
+	return fmt.Errorf("%s is not supported by replay", function)
+}
+
+var _ modbus.Client = (*Client)(nil)
@@ -0,0 +1,131 @@
+package replay_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/capture"
+	"github.com/michaelpeterswa/go-renogy-modbus/replay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func captureFile(t *testing.T, records []capture.Record) *bytes.Buffer {
+	var b bytes.Buffer
+	w, err := capture.NewWriter(&b, capture.Header{DeviceSerial: "1234"})
+	require.NoError(t, err)
+	for _, r := range records {
+		require.NoError(t, w.Write(r))
+	}
+	return &b
+}
+
+func TestReplay(t *testing.T) {
+	data := make([]byte, 70)
+	data[1] = 0x64 // 100% soc
+	data[3] = 0x88 // 13.6V
+	data[65] = byte(gorenogymodbus.FloatingChargingMode)
+	start := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+
+	c, err := replay.Open(captureFile(t, []capture.Record{
+		{Timestamp: start, SlaveID: 1, Address: 0x100, Data: data},
+		{Timestamp: start.Add(time.Second), SlaveID: 2, Address: 0x100, Data: make([]byte, 70)},
+		{Timestamp: start.Add(2 * time.Second), SlaveID: 1, Address: 0x100, Data: data},
+	}))
+	require.NoError(t, err)
+	c.SlaveID = 1
+	mc := &gorenogymodbus.ModbusClient{Client: c}
+
+	res, err := mc.ReadData()
+	require.NoError(t, err)
+	expected, err := gorenogymodbus.Parse(data)
+	require.NoError(t, err)
+	result, err := gorenogymodbus.Parse(res)
+	require.NoError(t, err)
+	assert.Equal(t, expected, result)
+
+	res, err = mc.ReadDataRange(0x101, 2)
+	require.NoError(t, err)
+	assert.Equal(t, data[2:6], res)
+
+	_, err = mc.ReadData()
+	assert.True(t, errors.Is(err, replay.ErrExhausted))
+
+	require.NoError(t, mc.SetLoad(true))
+	assert.Equal(t, []replay.Write{{Address: 0x10A, Values: []byte{0x00, 0x01}}}, c.Writes())
+}
+
+func TestReplayLoopAndTiming(t *testing.T) {
+	start := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	c := replay.New([]capture.Record{
+		{Timestamp: start, Address: 0xE002, Data: []byte{0x00, 0x64}},
+		{Timestamp: start.Add(50 * time.Millisecond), Address: 0xE002, Data: []byte{0x00, 0xC8}},
+	})
+	c.Realtime = true
+	c.Loop = true
+	mc := &gorenogymodbus.ModbusClient{Client: c}
+
+	began := time.Now()
+	var capacities []int
+	for i := 0; i < 3; i++ {
+		capacity, err := mc.ReadNominalBatteryCapacity()
+		require.NoError(t, err)
+		capacities = append(capacities, capacity)
+	}
+
+	assert.Equal(t, []int{100, 200, 100}, capacities)
+	assert.GreaterOrEqual(t, time.Since(began), 50*time.Millisecond)
+}
+
+func TestReplaySkipsCorruptRecords(t *testing.T) {
+	start := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	b := captureFile(t, []capture.Record{
+		{Timestamp: start, Address: 0xE002, Data: []byte{0x00, 0x64}},
+		{Timestamp: start.Add(time.Second), Address: 0xE002, Data: []byte{0x00, 0xC8}},
+		{Timestamp: start.Add(2 * time.Second), Address: 0xE002, Data: []byte{0x01, 0x2C}},
+	}).Bytes()
+	b[bytes.Index(b, []byte{0x00, 0xC8})+1] ^= 0x01
+
+	c, err := replay.Open(bytes.NewReader(b))
+	require.NoError(t, err)
+	mc := &gorenogymodbus.ModbusClient{Client: c}
+
+	var capacities []int
+	for i := 0; i < 2; i++ {
+		capacity, err := mc.ReadNominalBatteryCapacity()
+		require.NoError(t, err)
+		capacities = append(capacities, capacity)
+	}
+	assert.Equal(t, []int{100, 300}, capacities)
+
+	_, err = mc.ReadNominalBatteryCapacity()
+	assert.True(t, errors.Is(err, replay.ErrExhausted))
+}
+
+func TestReplayMiss(t *testing.T) {
+	start := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	records := []capture.Record{
+		{Timestamp: start, Address: 0xE002, Data: []byte{0x00, 0x64}},
+		{Timestamp: start.Add(time.Second), Address: 0xE002, Data: []byte{0x00, 0xC8}},
+	}
+
+	for _, loop := range []bool{false, true} {
+		c := replay.New(records)
+		c.Loop = loop
+		mc := &gorenogymodbus.ModbusClient{Client: c}
+
+		capacity, err := mc.ReadNominalBatteryCapacity()
+		require.NoError(t, err)
+		assert.Equal(t, 100, capacity)
+
+		_, err = mc.ReadData()
+		assert.True(t, errors.Is(err, replay.ErrExhausted))
+
+		capacity, err = mc.ReadNominalBatteryCapacity()
+		require.NoError(t, err)
+		assert.Equal(t, 200, capacity, "loop %t", loop)
+	}
+}
@@ -0,0 +1,299 @@
+package gorenogymodbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	nominalBatteryCapacityAddress uint16 = 0xE002
+	settingsAddress               uint16 = 0xE002
+	settingsQuantity              uint16 = 19
+	loadWorkingModeAddress        uint16 = 0xE01D
+
+	// AutoRecognizeSystemVoltage is the SystemVoltage that lets the controller detect it.
+	AutoRecognizeSystemVoltage = 0xFF
+
+	// maximumLoadWorkingMode is the highest load working mode: 0x00-0x0E are light
+	// control modes, 0x0F manual, 0x10 debug and 0x11 always on.
+	maximumLoadWorkingMode = 0x11
+)
+
+var (
+	// settings voltages are on a 12V basis
+	minimumSettingsVoltage = decimal.NewFromFloat(7)
+	maximumSettingsVoltage = decimal.NewFromFloat(17)
+)
+
+type BatteryType int
+
+const (
+	UserDefinedBattery BatteryType = iota
+	FloodedBattery
+	SealedBattery
+	GelBattery
+	LithiumBattery
+)
+
+func (bt BatteryType) String() string {
+	switch bt {
+	case UserDefinedBattery:
+		return "user defined"
+	case FloodedBattery:
+		return "flooded"
+	case SealedBattery:
+		return "sealed"
+	case GelBattery:
+		return "gel"
+	case LithiumBattery:
+		return "lithium"
+	default:
+		return "unknown"
+	}
+}
+
+func batteryTypeFromString(s string) BatteryType {
+	switch s {
+	case "user defined":
+		return UserDefinedBattery
+	case "flooded":
+		return FloodedBattery
+	case "sealed":
+		return SealedBattery
+	case "gel":
+		return GelBattery
+	case "lithium":
+		return LithiumBattery
+	default:
+		return -1
+	}
+}
+
+// Settings are the battery and charging parameters (0xE002-0xE014) and the load
+// working mode (0xE01D). Voltages are given for a 12V system, the controller scales
+// them by the system voltage.
+type Settings struct {
+	NominalBatteryCapacity        int             `json:"nominal_battery_capacity" yaml:"nominal_battery_capacity"`               // 0xE002
+	SystemVoltage                 int             `json:"system_voltage" yaml:"system_voltage"`                                   // 0xE003 (eight higher bits)
+	RecognizedVoltage             int             `json:"recognized_voltage" yaml:"recognized_voltage"`                           // 0xE003 (eight lower bits) read only
+	BatteryType                   string          `json:"battery_type" yaml:"battery_type"`                                       // 0xE004
+	OverVoltageThreshold          decimal.Decimal `json:"over_voltage_threshold" yaml:"over_voltage_threshold"`                   // 0xE005
+	ChargingLimitVoltage          decimal.Decimal `json:"charging_limit_voltage" yaml:"charging_limit_voltage"`                   // 0xE006
+	EqualizingChargingVoltage     decimal.Decimal `json:"equalizing_charging_voltage" yaml:"equalizing_charging_voltage"`         // 0xE007
+	BoostChargingVoltage          decimal.Decimal `json:"boost_charging_voltage" yaml:"boost_charging_voltage"`                   // 0xE008
+	FloatingChargingVoltage       decimal.Decimal `json:"floating_charging_voltage" yaml:"floating_charging_voltage"`             // 0xE009
+	BoostChargingRecoveryVoltage  decimal.Decimal `json:"boost_charging_recovery_voltage" yaml:"boost_charging_recovery_voltage"` // 0xE00A
+	OverDischargeRecoveryVoltage  decimal.Decimal `json:"over_discharge_recovery_voltage" yaml:"over_discharge_recovery_voltage"` // 0xE00B
+	UnderVoltageWarningLevel      decimal.Decimal `json:"under_voltage_warning_level" yaml:"under_voltage_warning_level"`         // 0xE00C
+	OverDischargeVoltage          decimal.Decimal `json:"over_discharge_voltage" yaml:"over_discharge_voltage"`                   // 0xE00D
+	DischargingLimitVoltage       decimal.Decimal `json:"discharging_limit_voltage" yaml:"discharging_limit_voltage"`             // 0xE00E
+	EndOfChargeSOC                int             `json:"end_of_charge_soc" yaml:"end_of_charge_soc"`                             // 0xE00F (eight higher bits)
+	EndOfDischargeSOC             int             `json:"end_of_discharge_soc" yaml:"end_of_discharge_soc"`                       // 0xE00F (eight lower bits)
+	OverDischargeTimeDelay        int             `json:"over_discharge_time_delay" yaml:"over_discharge_time_delay"`             // 0xE010 seconds
+	EqualizingChargingTime        int             `json:"equalizing_charging_time" yaml:"equalizing_charging_time"`               // 0xE011 minutes
+	BoostChargingTime             int             `json:"boost_charging_time" yaml:"boost_charging_time"`                         // 0xE012 minutes
+	EqualizingChargingInterval    int             `json:"equalizing_charging_interval" yaml:"equalizing_charging_interval"`       // 0xE013 days
+	TemperatureCompensationFactor int             `json:"temperature_compensation_factor" yaml:"temperature_compensation_factor"` // 0xE014 mV/°C/2V
+	LoadWorkingMode               int             `json:"load_working_mode" yaml:"load_working_mode"`                             // 0xE01D
+}
+
+// ReadNominalBatteryCapacity reads the nominal battery capacity in amp hours (0xE002).
+func (mc *ModbusClient) ReadNominalBatteryCapacity() (int, error) {
+	res, err := mc.readHoldingRegisters(nominalBatteryCapacityAddress, 1)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read holding registers: %w", err)
+	}
+
+	if len(res) != 2 {
+		return 0, fmt.Errorf("data length is not 2 bytes: %d", len(res))
+	}
+
+	return int(binary.BigEndian.Uint16(res)), nil
+}
+
+// ReadSettings reads the settings block (0xE002-0xE014) and the load working mode (0xE01D).
+func (mc *ModbusClient) ReadSettings() (*Settings, error) {
+	res, err := mc.readHoldingRegisters(settingsAddress, settingsQuantity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read holding registers: %w", err)
+	}
+
+	mode, err := mc.readHoldingRegisters(loadWorkingModeAddress, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read holding registers: %w", err)
+	}
+
+	return ParseSettings(append(res, mode...))
+}
+
+// ParseSettings decodes the settings block followed by the load working mode register.
+func ParseSettings(dataBytes []byte) (*Settings, error) {
+	if len(dataBytes) != int(settingsQuantity+1)*2 {
+		return nil, fmt.Errorf("data length is not %d bytes: %d", (settingsQuantity+1)*2, len(dataBytes))
+	}
+
+	return &Settings{
+		NominalBatteryCapacity:        int(binary.BigEndian.Uint16(dataBytes[0:2])),                                         // 0xE002
+		SystemVoltage:                 int(dataBytes[2]),                                                                    // 0xE003 first byte
+		RecognizedVoltage:             int(dataBytes[3]),                                                                    // 0xE003 second byte
+		BatteryType:                   BatteryType(binary.BigEndian.Uint16(dataBytes[4:6])).String(),                        // 0xE004
+		OverVoltageThreshold:          decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[6:8])) * 0.1),   // 0xE005
+		ChargingLimitVoltage:          decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[8:10])) * 0.1),  // 0xE006
+		EqualizingChargingVoltage:     decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[10:12])) * 0.1), // 0xE007
+		BoostChargingVoltage:          decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[12:14])) * 0.1), // 0xE008
+		FloatingChargingVoltage:       decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[14:16])) * 0.1), // 0xE009
+		BoostChargingRecoveryVoltage:  decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[16:18])) * 0.1), // 0xE00A
+		OverDischargeRecoveryVoltage:  decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[18:20])) * 0.1), // 0xE00B
+		UnderVoltageWarningLevel:      decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[20:22])) * 0.1), // 0xE00C
+		OverDischargeVoltage:          decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[22:24])) * 0.1), // 0xE00D
+		DischargingLimitVoltage:       decimalFloatingPointFixed2(float64(binary.BigEndian.Uint16(dataBytes[24:26])) * 0.1), // 0xE00E
+		EndOfChargeSOC:                int(dataBytes[26]),                                                                   // 0xE00F first byte
+		EndOfDischargeSOC:             int(dataBytes[27]),                                                                   // 0xE00F second byte
+		OverDischargeTimeDelay:        int(binary.BigEndian.Uint16(dataBytes[28:30])),                                       // 0xE010
+		EqualizingChargingTime:        int(binary.BigEndian.Uint16(dataBytes[30:32])),                                       // 0xE011
+		BoostChargingTime:             int(binary.BigEndian.Uint16(dataBytes[32:34])),                                       // 0xE012
+		EqualizingChargingInterval:    int(binary.BigEndian.Uint16(dataBytes[34:36])),                                       // 0xE013
+		TemperatureCompensationFactor: int(binary.BigEndian.Uint16(dataBytes[36:38])),                                       // 0xE014
+		LoadWorkingMode:               int(binary.BigEndian.Uint16(dataBytes[38:40])),                                       // 0xE01D
+	}, nil
+}
+
+// Synthesize encodes the settings block followed by the load working mode register,
+// in the layout accepted by ParseSettings.
+func (s *Settings) Synthesize() ([]byte, error) {
+	var data []byte
+
+	batteryType := batteryTypeFromString(s.BatteryType)
+	if batteryType < 0 {
+		return nil, fmt.Errorf("invalid battery type: %s", s.BatteryType)
+	}
+
+	data = binary.BigEndian.AppendUint16(data, uint16(s.NominalBatteryCapacity))
+	data = append(data, byte(s.SystemVoltage), byte(s.RecognizedVoltage))
+	data = binary.BigEndian.AppendUint16(data, uint16(batteryType))
+
+	for _, v := range []decimal.Decimal{
+		s.OverVoltageThreshold,
+		s.ChargingLimitVoltage,
+		s.EqualizingChargingVoltage,
+		s.BoostChargingVoltage,
+		s.FloatingChargingVoltage,
+		s.BoostChargingRecoveryVoltage,
+		s.OverDischargeRecoveryVoltage,
+		s.UnderVoltageWarningLevel,
+		s.OverDischargeVoltage,
+		s.DischargingLimitVoltage,
+	} {
+		data = binary.BigEndian.AppendUint16(data, uint16(v.Div(decimal.NewFromFloat(0.1)).Round(0).IntPart()))
+	}
+
+	data = append(data, byte(s.EndOfChargeSOC), byte(s.EndOfDischargeSOC))
+	data = binary.BigEndian.AppendUint16(data, uint16(s.OverDischargeTimeDelay))
+	data = binary.BigEndian.AppendUint16(data, uint16(s.EqualizingChargingTime))
+	data = binary.BigEndian.AppendUint16(data, uint16(s.BoostChargingTime))
+	data = binary.BigEndian.AppendUint16(data, uint16(s.EqualizingChargingInterval))
+	data = binary.BigEndian.AppendUint16(data, uint16(s.TemperatureCompensationFactor))
+	data = binary.BigEndian.AppendUint16(data, uint16(s.LoadWorkingMode))
+
+	if len(data) != int(settingsQuantity+1)*2 {
+		return nil, fmt.Errorf("invalid settings byte slice length: %d", len(data))
+	}
+	return data, nil
+}
+
+// Validate checks the settings are in range and the charging and discharging
+// voltages are in the order the controller requires.
+func (s *Settings) Validate() error {
+	errs := s.validateCharging()
+
+	if s.NominalBatteryCapacity <= 0 || s.NominalBatteryCapacity > 0xFFFF {
+		errs = append(errs, fmt.Errorf("invalid nominal battery capacity: %d", s.NominalBatteryCapacity))
+	}
+	if s.EndOfChargeSOC < 0 || s.EndOfChargeSOC > 100 || s.EndOfDischargeSOC < 0 || s.EndOfDischargeSOC >= s.EndOfChargeSOC {
+		errs = append(errs, fmt.Errorf("invalid end of charge and discharge soc: %d, %d", s.EndOfChargeSOC, s.EndOfDischargeSOC))
+	}
+	if s.LoadWorkingMode < 0 || s.LoadWorkingMode > maximumLoadWorkingMode {
+		errs = append(errs, fmt.Errorf("invalid load working mode: %d", s.LoadWorkingMode))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateCharging checks the battery type, system voltage and the charging
+// parameters a Profile sets.
+func (s *Settings) validateCharging() []error {
+	var errs []error
+
+	if batteryTypeFromString(s.BatteryType) < 0 {
+		errs = append(errs, fmt.Errorf("invalid battery type: %s", s.BatteryType))
+	}
+	switch s.SystemVoltage {
+	case 12, 24, 36, 48, AutoRecognizeSystemVoltage:
+	default:
+		errs = append(errs, fmt.Errorf("invalid system voltage: %d", s.SystemVoltage))
+	}
+	voltages := []struct {
+		name  string
+		value decimal.Decimal
+	}{
+		{"over_voltage_threshold", s.OverVoltageThreshold},
+		{"charging_limit_voltage", s.ChargingLimitVoltage},
+		{"equalizing_charging_voltage", s.EqualizingChargingVoltage},
+		{"boost_charging_voltage", s.BoostChargingVoltage},
+		{"floating_charging_voltage", s.FloatingChargingVoltage},
+		{"boost_charging_recovery_voltage", s.BoostChargingRecoveryVoltage},
+		{"over_discharge_recovery_voltage", s.OverDischargeRecoveryVoltage},
+		{"under_voltage_warning_level", s.UnderVoltageWarningLevel},
+		{"over_discharge_voltage", s.OverDischargeVoltage},
+		{"discharging_limit_voltage", s.DischargingLimitVoltage},
+	}
+	for _, v := range voltages {
+		if v.value.LessThan(minimumSettingsVoltage) || v.value.GreaterThan(maximumSettingsVoltage) {
+			errs = append(errs, fmt.Errorf("%s out of range %s-%sV: %s", v.name, minimumSettingsVoltage, maximumSettingsVoltage, v.value))
+		}
+	}
+
+	order := []struct {
+		higher, lower int
+		strict        bool
+	}{
+		{0, 1, true},  // over voltage > charging limit
+		{1, 2, false}, // charging limit >= equalizing
+		{2, 3, false}, // equalizing >= boost
+		{3, 4, false}, // boost >= float
+		{4, 5, true},  // float > boost recovery
+		{5, 6, true},  // boost recovery > over discharge recovery
+		{6, 8, true},  // over discharge recovery > over discharge
+		{7, 8, true},  // under voltage warning > over discharge
+		{8, 9, false}, // over discharge >= discharging limit
+	}
+	for _, o := range order {
+		higher, lower := voltages[o.higher], voltages[o.lower]
+		if lower.value.GreaterThan(higher.value) || (o.strict && lower.value.Equal(higher.value)) {
+			relation := ">="
+			if o.strict {
+				relation = ">"
+			}
+			errs = append(errs, fmt.Errorf("%s must be %s %s: %s, %s", higher.name, relation, lower.name, higher.value, lower.value))
+		}
+	}
+
+	for _, v := range []struct {
+		name  string
+		value int
+	}{
+		{"over_discharge_time_delay", s.OverDischargeTimeDelay},
+		{"equalizing_charging_time", s.EqualizingChargingTime},
+		{"boost_charging_time", s.BoostChargingTime},
+		{"equalizing_charging_interval", s.EqualizingChargingInterval},
+		{"temperature_compensation_factor", s.TemperatureCompensationFactor},
+	} {
+		if v.value < 0 || v.value > 0xFFFF {
+			errs = append(errs, fmt.Errorf("invalid %s: %d", v.name, v.value))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,124 @@
+package gorenogymodbus_test
+
+import (
+	"testing"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// sealedSettings are the factory settings of a Rover for a sealed battery.
+func sealedSettings() gorenogymodbus.Settings {
+	return gorenogymodbus.Settings{
+		NominalBatteryCapacity:        100,
+		SystemVoltage:                 gorenogymodbus.AutoRecognizeSystemVoltage,
+		RecognizedVoltage:             12,
+		BatteryType:                   gorenogymodbus.SealedBattery.String(),
+		OverVoltageThreshold:          decimal.NewFromFloat(16),
+		ChargingLimitVoltage:          decimal.NewFromFloat(15.5),
+		EqualizingChargingVoltage:     decimal.NewFromFloat(14.6),
+		BoostChargingVoltage:          decimal.NewFromFloat(14.4),
+		FloatingChargingVoltage:       decimal.NewFromFloat(13.8),
+		BoostChargingRecoveryVoltage:  decimal.NewFromFloat(13.2),
+		OverDischargeRecoveryVoltage:  decimal.NewFromFloat(12.6),
+		UnderVoltageWarningLevel:      decimal.NewFromFloat(12),
+		OverDischargeVoltage:          decimal.NewFromFloat(11.1),
+		DischargingLimitVoltage:       decimal.NewFromFloat(10.6),
+		EndOfChargeSOC:                100,
+		EndOfDischargeSOC:             50,
+		OverDischargeTimeDelay:        5,
+		EqualizingChargingTime:        120,
+		BoostChargingTime:             120,
+		EqualizingChargingInterval:    30,
+		TemperatureCompensationFactor: 5,
+		LoadWorkingMode:               0x0F,
+	}
+}
+
+func TestSettings(t *testing.T) {
+	settings := sealedSettings()
+
+	b, err := settings.Synthesize()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x00, 0x64, 0xff, 0x0c, 0x00,
+		0x02, 0x00, 0xa0, 0x00, 0x9b,
+		0x00, 0x92, 0x00, 0x90, 0x00,
+		0x8a, 0x00, 0x84, 0x00, 0x7e,
+		0x00, 0x78, 0x00, 0x6f, 0x00,
+		0x6a, 0x64, 0x32, 0x00, 0x05,
+		0x00, 0x78, 0x00, 0x78, 0x00,
+		0x1e, 0x00, 0x05, 0x00, 0x0f,
+	}, b)
+
+	result, err := gorenogymodbus.ParseSettings(b)
+	assert.NoError(t, err)
+	assert.Equal(t, settings.BatteryType, result.BatteryType)
+	assert.True(t, settings.FloatingChargingVoltage.Equal(result.FloatingChargingVoltage))
+	assert.Equal(t, settings.LoadWorkingMode, result.LoadWorkingMode)
+
+	settings.BatteryType = "lead"
+	_, err = settings.Synthesize()
+	assert.Error(t, err)
+}
+
+func TestSettingsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(s *gorenogymodbus.Settings)
+		expected string
+	}{
+		{
+			name:   "valid",
+			modify: func(s *gorenogymodbus.Settings) {},
+		},
+		{
+			name:     "battery type",
+			modify:   func(s *gorenogymodbus.Settings) { s.BatteryType = "lead" },
+			expected: "invalid battery type: lead",
+		},
+		{
+			name:     "system voltage",
+			modify:   func(s *gorenogymodbus.Settings) { s.SystemVoltage = 13 },
+			expected: "invalid system voltage: 13",
+		},
+		{
+			name:     "voltage out of range",
+			modify:   func(s *gorenogymodbus.Settings) { s.OverVoltageThreshold = decimal.NewFromFloat(17.5) },
+			expected: "over_voltage_threshold out of range 7-17V: 17.5",
+		},
+		{
+			name:     "float above boost",
+			modify:   func(s *gorenogymodbus.Settings) { s.FloatingChargingVoltage = decimal.NewFromFloat(14.5) },
+			expected: "boost_charging_voltage must be >= floating_charging_voltage: 14.4, 14.5",
+		},
+		{
+			name:     "boost recovery equal to float",
+			modify:   func(s *gorenogymodbus.Settings) { s.BoostChargingRecoveryVoltage = decimal.NewFromFloat(13.8) },
+			expected: "floating_charging_voltage must be > boost_charging_recovery_voltage: 13.8, 13.8",
+		},
+		{
+			name: "several",
+			modify: func(s *gorenogymodbus.Settings) {
+				s.EndOfDischargeSOC = 100
+				s.LoadWorkingMode = 0x12
+			},
+			expected: "invalid end of charge and discharge soc: 100, 100\ninvalid load working mode: 18",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			settings := sealedSettings()
+			tc.modify(&settings)
+
+			err := settings.Validate()
+			if tc.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package simulator
+
+import (
+	"errors"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// ErrNoResponse is returned by a Client when the injector drops a response.
+var ErrNoResponse = errors.New("no response")
+
+// Client returns a Modbus client that talks to the server in memory, going through
+// RTU framing, checksums and the injector like requests over a pseudo-terminal do.
+func (s *Server) Client() modbus.Client {
+	packager := modbus.NewRTUClientHandler("")
+	packager.SlaveId = s.SlaveID
+	return modbus.NewClient2(packager, transporter{s})
+}
+
+type transporter struct {
+	server *Server
+}
+
+func (t transporter) Send(request []byte) ([]byte, error) {
+	response := t.server.Handle(request)
+	var delay time.Duration
+	if t.server.Injector != nil {
+		response, delay = t.server.Injector.Apply(request, response)
+	}
+	time.Sleep(delay)
+
+	if response == nil {
+		return nil, ErrNoResponse
+	}
+	return response, nil
+}
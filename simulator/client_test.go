@@ -0,0 +1,33 @@
+package simulator_test
+
+import (
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/simulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerClient(t *testing.T) {
+	start := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	m, _ := newModel(t, start, simulator.ModelConfig{})
+	s := simulator.NewServer(1, m)
+	s.Injector = simulator.NewInjector(1)
+	s.Injector.Add(simulator.Rule{Injection: simulator.DropResponse, After: 2, Count: 1})
+	mc := &gorenogymodbus.ModbusClient{Client: s.Client()}
+
+	pi, err := mc.ReadProductInformation()
+	require.NoError(t, err)
+	assert.Equal(t, "RNG-CTRL-RVR40", pi.Model)
+
+	require.NoError(t, mc.SetLoad(true))
+	assert.True(t, m.Reading().StreetLightStatus)
+
+	_, err = mc.ReadData()
+	assert.ErrorIs(t, err, simulator.ErrNoResponse)
+
+	_, err = mc.Client.ReadHoldingRegisters(0x2000, 1)
+	assert.ErrorContains(t, err, "exception '2'")
+}
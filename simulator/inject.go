@@ -0,0 +1,157 @@
+package simulator
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Injection is a way a response can go wrong on the wire.
+type Injection int
+
+const (
+	// DropResponse never answers the request.
+	DropResponse Injection = iota
+	// CorruptChecksum flips a bit of the response checksum.
+	CorruptChecksum
+	// TruncateResponse sends only the first half of the response.
+	TruncateResponse
+	// DelayResponse answers after the rule's Delay.
+	DelayResponse
+	// ExceptionResponse answers with the rule's Exception instead.
+	ExceptionResponse
+)
+
+func (i Injection) String() string {
+	switch i {
+	case DropResponse:
+		return "drop response"
+	case CorruptChecksum:
+		return "corrupt checksum"
+	case TruncateResponse:
+		return "truncate response"
+	case DelayResponse:
+		return "delay response"
+	case ExceptionResponse:
+		return "exception response"
+	default:
+		return "unknown"
+	}
+}
+
+// Rule applies an Injection to the responses of matching requests.
+type Rule struct {
+	Injection Injection
+
+	// Match selects requests by function code and starting address. Nil matches all.
+	Match func(function byte, address uint16) bool
+	// Probability of applying to a matching request, zero means always.
+	Probability float64
+	// After skips the first matching requests.
+	After int
+	// Every applies to every nth matching request after those skipped, zero means every one.
+	Every int
+	// Count limits how many times the rule applies, zero means no limit.
+	Count int
+
+	Delay     time.Duration // for DelayResponse
+	Exception Exception     // for ExceptionResponse, defaults to ExceptionServerDeviceFailure
+}
+
+type rule struct {
+	Rule
+	matched int
+	applied int
+}
+
+// Injector corrupts responses of a Server according to its rules. It is safe for
+// concurrent use, rules can be added and cleared while serving.
+type Injector struct {
+	mu    sync.Mutex
+	rules []*rule
+	rand  *rand.Rand
+}
+
+func NewInjector(seed int64) *Injector {
+	return &Injector{rand: rand.New(rand.NewSource(seed))}
+}
+
+func (in *Injector) Add(r Rule) {
+	if r.Injection == ExceptionResponse && r.Exception == 0 {
+		r.Exception = ExceptionServerDeviceFailure
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.rules = append(in.rules, &rule{Rule: r})
+}
+
+func (in *Injector) Clear() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.rules = nil
+}
+
+// Apply returns the response to send for a request and how long to wait before
+// sending it. A nil response means nothing is sent.
+func (in *Injector) Apply(request, response []byte) ([]byte, time.Duration) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if len(request) < 4 || response == nil {
+		return response, 0
+	}
+	function := request[1]
+	address := binary.BigEndian.Uint16(request[2:4])
+
+	var delay time.Duration
+	for _, r := range in.rules {
+		if !r.applies(function, address, in.rand) {
+			continue
+		}
+
+		switch r.Injection {
+		case DropResponse:
+			return nil, delay
+		case CorruptChecksum:
+			response = append([]byte(nil), response...)
+			response[len(response)-1] ^= 0x01
+		case TruncateResponse:
+			response = response[:len(response)/2]
+		case DelayResponse:
+			delay += r.Delay
+		case ExceptionResponse:
+			response = []byte{response[0], function | 0x80, byte(r.Exception)}
+			response = binary.LittleEndian.AppendUint16(response, crc16(response))
+		}
+	}
+
+	return response, delay
+}
+
+func (r *rule) applies(function byte, address uint16, rnd *rand.Rand) bool {
+	if r.Match != nil && !r.Match(function, address) {
+		return false
+	}
+	if r.Count > 0 && r.applied >= r.Count {
+		return false
+	}
+
+	r.matched++
+	n := r.matched - r.After
+	if n <= 0 {
+		return false
+	}
+	if r.Every > 1 && n%r.Every != 0 {
+		return false
+	}
+	if r.Probability > 0 && rnd.Float64() >= r.Probability {
+		return false
+	}
+
+	r.applied++
+	return true
+}
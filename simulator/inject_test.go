@@ -0,0 +1,170 @@
+package simulator_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/simulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjectorApply(t *testing.T) {
+	request := []byte{0x01, 0x03, 0x01, 0x00, 0x00, 0x01, 0x85, 0xf6}
+	response := []byte{0x01, 0x03, 0x02, 0x00, 0x64, 0xb9, 0xaf}
+
+	type result struct {
+		response []byte
+		delay    time.Duration
+	}
+
+	tests := []struct {
+		name     string
+		rules    []simulator.Rule
+		expected []result
+	}{
+		{
+			name:     "no rules",
+			expected: []result{{response: response}},
+		},
+		{
+			name:     "drop",
+			rules:    []simulator.Rule{{Injection: simulator.DropResponse}},
+			expected: []result{{}, {}},
+		},
+		{
+			name:     "corrupt checksum",
+			rules:    []simulator.Rule{{Injection: simulator.CorruptChecksum, Count: 1}},
+			expected: []result{{response: []byte{0x01, 0x03, 0x02, 0x00, 0x64, 0xb9, 0xae}}, {response: response}},
+		},
+		{
+			name:     "truncate",
+			rules:    []simulator.Rule{{Injection: simulator.TruncateResponse}},
+			expected: []result{{response: response[:3]}},
+		},
+		{
+			name:     "delay",
+			rules:    []simulator.Rule{{Injection: simulator.DelayResponse, Delay: time.Second}},
+			expected: []result{{response: response, delay: time.Second}},
+		},
+		{
+			name:     "exception",
+			rules:    []simulator.Rule{{Injection: simulator.ExceptionResponse, Exception: simulator.ExceptionServerDeviceFailure}},
+			expected: []result{{response: []byte{0x01, 0x83, 0x04, 0x40, 0xf3}}},
+		},
+		{
+			name:     "exception defaults to server device failure",
+			rules:    []simulator.Rule{{Injection: simulator.ExceptionResponse}},
+			expected: []result{{response: []byte{0x01, 0x83, 0x04, 0x40, 0xf3}}},
+		},
+		{
+			name:     "after and every",
+			rules:    []simulator.Rule{{Injection: simulator.DropResponse, After: 1, Every: 2}},
+			expected: []result{{response: response}, {response: response}, {}, {response: response}, {}},
+		},
+		{
+			name: "match",
+			rules: []simulator.Rule{{
+				Injection: simulator.DropResponse,
+				Match:     func(function byte, address uint16) bool { return address == 0xE002 },
+			}},
+			expected: []result{{response: response}},
+		},
+		{
+			name: "delay then drop",
+			rules: []simulator.Rule{
+				{Injection: simulator.DelayResponse, Delay: time.Second},
+				{Injection: simulator.DropResponse},
+			},
+			expected: []result{{delay: time.Second}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			in := simulator.NewInjector(1)
+			for _, r := range tc.rules {
+				in.Add(r)
+			}
+
+			for _, e := range tc.expected {
+				res, delay := in.Apply(request, response)
+				assert.Equal(t, e.response, res)
+				assert.Equal(t, e.delay, delay)
+			}
+		})
+	}
+}
+
+func TestInjectorProbability(t *testing.T) {
+	request := []byte{0x01, 0x03, 0x01, 0x00, 0x00, 0x01, 0x85, 0xf6}
+	response := []byte{0x01, 0x03, 0x02, 0x00, 0x64, 0xb9, 0xaf}
+
+	in := simulator.NewInjector(1)
+	in.Add(simulator.Rule{Injection: simulator.DropResponse, Probability: 0.25})
+
+	var dropped int
+	for i := 0; i < 1000; i++ {
+		if res, _ := in.Apply(request, response); res == nil {
+			dropped++
+		}
+	}
+	assert.InDelta(t, 250, dropped, 50)
+}
+
+func TestModelFaults(t *testing.T) {
+	start := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	m, clock := newModel(t, start, simulator.ModelConfig{})
+
+	require.NoError(t, m.RaiseFault(gorenogymodbus.BatteryOverVoltage))
+	require.NoError(t, m.ScheduleFault(gorenogymodbus.LoadShortCircuit, start.Add(time.Minute), start.Add(2*time.Minute)))
+	assert.EqualError(t, m.RaiseFault(gorenogymodbus.NoFault), "invalid controller fault: 0")
+	assert.Equal(t, []string{gorenogymodbus.BatteryOverVoltage.String()}, m.Reading().ControllerFaults)
+
+	clock.now = start.Add(90 * time.Second)
+	m.Sync()
+	assert.Equal(t, []string{gorenogymodbus.BatteryOverVoltage.String(), gorenogymodbus.LoadShortCircuit.String()}, m.Reading().ControllerFaults)
+
+	clock.now = start.Add(2 * time.Minute)
+	m.Sync()
+	require.NoError(t, m.ClearFault(gorenogymodbus.BatteryOverVoltage))
+	assert.Empty(t, m.Reading().ControllerFaults)
+}
+
+func TestServerInjectionPTY(t *testing.T) {
+	start := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	model := simulator.NewModel(simulator.ModelConfig{Clock: func() time.Time { return start }})
+
+	p, err := simulator.OpenPTY()
+	require.NoError(t, err)
+	defer p.Close()
+
+	in := simulator.NewInjector(1)
+	in.Add(simulator.Rule{Injection: simulator.CorruptChecksum, Count: 1})
+	in.Add(simulator.Rule{Injection: simulator.ExceptionResponse, Exception: simulator.ExceptionServerDeviceFailure, After: 2, Count: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := simulator.NewServer(1, model)
+	s.Injector = in
+	go s.Serve(ctx, p) //nolint:errcheck
+
+	mc, err := gorenogymodbus.NewModbusClient(log.New(io.Discard, "", 0), p.Path(), time.Minute)
+	require.NoError(t, err)
+
+	_, err = mc.ReadData()
+	assert.ErrorContains(t, err, "crc")
+
+	_, err = mc.ReadData()
+	assert.NoError(t, err)
+
+	_, err = mc.ReadData()
+	assert.ErrorContains(t, err, "exception '4'")
+
+	_, err = mc.ReadData()
+	assert.NoError(t, err)
+}
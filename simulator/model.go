@@ -0,0 +1,584 @@
+// Package simulator simulates a Renogy Rover charge controller: a model of a solar
+// panel, battery and load behind the controller's Modbus registers, served as a
+// Modbus RTU slave on a pseudo-terminal.
+package simulator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	productAddress  uint16 = 0x0A
+	productQuantity uint16 = 17
+	dynamicAddress  uint16 = 0x100
+	dynamicQuantity uint16 = 35
+	loadCommand     uint16 = 0x10A
+	settingsAddress uint16 = 0xE002
+	settingsEnd     uint16 = 0xE014
+	loadModeAddress uint16 = 0xE01D
+	historyAddress  uint16 = 0xF000
+	historyQuantity uint16 = 10
+
+	// maxStep is the longest simulated interval integrated in one step.
+	maxStep = time.Minute
+)
+
+type ModelConfig struct {
+	// Clock returns the simulated time. Defaults to the wall clock sped up by TimeScale.
+	Clock func() time.Time
+	// TimeScale is the simulated seconds per wall clock second used by the default Clock.
+	TimeScale float64
+
+	Sunrise                 float64 // hour of the day, defaults to 6
+	Sunset                  float64 // hour of the day, defaults to 20
+	PeakSolarPower          float64 // watts at solar noon, defaults to 400
+	PanelOpenCircuitVoltage float64 // volts, defaults to 22
+	BatteryCapacity         int     // amp hours, defaults to 100
+	InitialSOC              float64 // percent, defaults to 60
+	LoadPower               float64 // watts drawn while the load is on, defaults to 20
+	LoadOn                  bool
+
+	Product  *gorenogymodbus.ProductInformation // defaults to a 40A Rover
+	Settings *gorenogymodbus.Settings           // defaults to sealed lead acid values
+}
+
+func (c *ModelConfig) setDefaults() {
+	if c.TimeScale <= 0 {
+		c.TimeScale = 1
+	}
+	if c.Clock == nil {
+		start := time.Now()
+		scale := c.TimeScale
+		c.Clock = func() time.Time {
+			return start.Add(time.Duration(float64(time.Since(start)) * scale))
+		}
+	}
+	if c.Sunrise == 0 && c.Sunset == 0 {
+		c.Sunrise, c.Sunset = 6, 20
+	}
+	if c.PeakSolarPower == 0 {
+		c.PeakSolarPower = 400
+	}
+	if c.PanelOpenCircuitVoltage == 0 {
+		c.PanelOpenCircuitVoltage = 22
+	}
+	if c.BatteryCapacity == 0 {
+		c.BatteryCapacity = 100
+	}
+	if c.InitialSOC == 0 {
+		c.InitialSOC = 60
+	}
+	if c.LoadPower == 0 {
+		c.LoadPower = 20
+	}
+	if c.Product == nil {
+		c.Product = &gorenogymodbus.ProductInformation{
+			MaximumVoltageSupported: 24,
+			RatedChargingCurrent:    40,
+			RatedDischargingCurrent: 20,
+			ProductType:             gorenogymodbus.ProductTypeController.String(),
+			Model:                   "RNG-CTRL-RVR40",
+			SoftwareVersion:         "V1.0.4",
+			HardwareVersion:         "V1.0.0",
+			SerialNumber:            "20230810",
+			DeviceAddress:           1,
+		}
+	}
+	if c.Settings == nil {
+		c.Settings = &gorenogymodbus.Settings{
+			NominalBatteryCapacity:        c.BatteryCapacity,
+			SystemVoltage:                 gorenogymodbus.AutoRecognizeSystemVoltage,
+			RecognizedVoltage:             12,
+			BatteryType:                   gorenogymodbus.SealedBattery.String(),
+			OverVoltageThreshold:          decimal.NewFromFloat(16),
+			ChargingLimitVoltage:          decimal.NewFromFloat(15.5),
+			EqualizingChargingVoltage:     decimal.NewFromFloat(14.6),
+			BoostChargingVoltage:          decimal.NewFromFloat(14.4),
+			FloatingChargingVoltage:       decimal.NewFromFloat(13.8),
+			BoostChargingRecoveryVoltage:  decimal.NewFromFloat(13.2),
+			OverDischargeRecoveryVoltage:  decimal.NewFromFloat(12.6),
+			UnderVoltageWarningLevel:      decimal.NewFromFloat(12),
+			OverDischargeVoltage:          decimal.NewFromFloat(11.1),
+			DischargingLimitVoltage:       decimal.NewFromFloat(10.6),
+			EndOfChargeSOC:                100,
+			EndOfDischargeSOC:             50,
+			OverDischargeTimeDelay:        5,
+			EqualizingChargingTime:        120,
+			BoostChargingTime:             120,
+			EqualizingChargingInterval:    30,
+			TemperatureCompensationFactor: 5,
+			LoadWorkingMode:               0x0F,
+		}
+	}
+}
+
+// dayTotals accumulates the current day fields of the controller.
+type dayTotals struct {
+	minVoltage, maxVoltage            float64
+	maxChargeCurrent, maxLoadCurrent  float64
+	maxChargePower, maxLoadPower      float64
+	chargeAmpHours, dischargeAmpHours float64
+	generationKWh, consumptionKWh     float64
+}
+
+// Model is the simulated state of the controller and what is connected to it.
+// It is safe for concurrent use.
+type Model struct {
+	mu     sync.Mutex
+	config ModelConfig
+
+	now      time.Time
+	soc      float64
+	loadOn   bool
+	faults   uint32
+	windows  []faultWindow
+	product  gorenogymodbus.ProductInformation
+	settings gorenogymodbus.Settings
+
+	// instantaneous values of the last step
+	batteryVoltage, chargeCurrent, chargePower float64
+	loadCurrent, loadPower                     float64
+	panelVoltage, panelCurrent                 float64
+	chargingState                              gorenogymodbus.ChargingState
+
+	today         dayTotals
+	history       []dayTotals // previous days, most recent first
+	operatingDays int
+	overDischarge int
+	fullCharges   int
+	totalChargeAh float64
+	totalLoadAh   float64
+	totalGenKWh   float64
+	totalConsKWh  float64
+}
+
+func NewModel(config ModelConfig) *Model {
+	config.setDefaults()
+
+	m := &Model{
+		config:        config,
+		now:           config.Clock(),
+		soc:           config.InitialSOC,
+		loadOn:        config.LoadOn,
+		product:       *config.Product,
+		settings:      *config.Settings,
+		operatingDays: 1,
+	}
+	m.step(0)
+	m.today = dayTotals{minVoltage: m.batteryVoltage, maxVoltage: m.batteryVoltage}
+
+	return m
+}
+
+// Now returns the simulated time the model has been advanced to.
+func (m *Model) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.now
+}
+
+// Sync advances the model to the time of its clock.
+func (m *Model) Sync() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.advance(m.config.Clock())
+}
+
+// SetLoad switches the load like the load command register does.
+func (m *Model) SetLoad(on bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.loadOn = on
+}
+
+// SetFaults sets the raw controller fault bits of 0x121-0x122.
+func (m *Model) SetFaults(faults uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.faults = faults
+}
+
+// RaiseFault sets the bit of a controller fault until it is cleared.
+func (m *Model) RaiseFault(fault gorenogymodbus.ControllerFault) error {
+	bit, err := faultBit(fault)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.faults |= 1 << bit
+	return nil
+}
+
+// ClearFault clears the bit of a controller fault.
+func (m *Model) ClearFault(fault gorenogymodbus.ControllerFault) error {
+	bit, err := faultBit(fault)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.faults &^= 1 << bit
+	return nil
+}
+
+// ScheduleFault raises a controller fault from one simulated time until another.
+func (m *Model) ScheduleFault(fault gorenogymodbus.ControllerFault, from, until time.Time) error {
+	bit, err := faultBit(fault)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.windows = append(m.windows, faultWindow{bit: bit, from: from, until: until})
+	return nil
+}
+
+type faultWindow struct {
+	bit         uint
+	from, until time.Time
+}
+
+func faultBit(fault gorenogymodbus.ControllerFault) (uint, error) {
+	for bit, f := range gorenogymodbus.ControllerFaultsMap {
+		if f == fault {
+			return uint(bit), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid controller fault: %d", fault)
+}
+
+// activeFaults returns the fault bits raised by hand or by schedule.
+func (m *Model) activeFaults() uint32 {
+	faults := m.faults
+	for _, w := range m.windows {
+		if !m.now.Before(w.from) && m.now.Before(w.until) {
+			faults |= 1 << w.bit
+		}
+	}
+	return faults
+}
+
+// Reading returns the dynamic controller information of the current state.
+func (m *Model) Reading() *gorenogymodbus.DynamicControllerInformation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.reading()
+}
+
+func (m *Model) advance(to time.Time) {
+	for m.now.Before(to) {
+		dt := to.Sub(m.now)
+		if dt > maxStep {
+			dt = maxStep
+		}
+
+		before := m.hourOfDay()
+		m.now = m.now.Add(dt)
+		if before < m.config.Sunrise && m.hourOfDay() >= m.config.Sunrise {
+			m.newDay()
+		}
+
+		m.step(dt)
+	}
+}
+
+func (m *Model) hourOfDay() float64 {
+	h, min, s := m.now.Clock()
+	return float64(h) + float64(min)/60 + float64(s)/3600
+}
+
+// solarPower follows a half sine between sunrise and sunset.
+func (m *Model) solarPower() float64 {
+	h := m.hourOfDay()
+	if h <= m.config.Sunrise || h >= m.config.Sunset {
+		return 0
+	}
+	return m.config.PeakSolarPower * math.Sin(math.Pi*(h-m.config.Sunrise)/(m.config.Sunset-m.config.Sunrise))
+}
+
+func (m *Model) step(dt time.Duration) {
+	hours := dt.Hours()
+	capacity := float64(m.config.BatteryCapacity)
+	boost := m.settings.BoostChargingVoltage.InexactFloat64()
+	float := m.settings.FloatingChargingVoltage.InexactFloat64()
+
+	// resting voltage of a 12V battery, raised while charging
+	restVoltage := 11.8 + 1.2*m.soc/100
+
+	available := m.solarPower()
+	m.panelVoltage = 0
+	if available > 0 {
+		m.panelVoltage = m.config.PanelOpenCircuitVoltage * 0.82
+	}
+
+	m.loadPower, m.loadCurrent = 0, 0
+	if m.loadOn {
+		m.loadPower = m.config.LoadPower
+		m.loadCurrent = m.loadPower / restVoltage
+	}
+
+	maxCharge := float64(m.product.RatedChargingCurrent) * boost
+	m.chargePower = math.Min(available*0.96, maxCharge)
+	switch {
+	case available <= 0:
+		m.chargingState = gorenogymodbus.ChargingDeactivated
+		m.batteryVoltage = restVoltage
+	case m.soc >= 100:
+		// only supply the load once full
+		m.chargingState = gorenogymodbus.FloatingChargingMode
+		m.chargePower = math.Min(m.chargePower, m.loadPower)
+		m.batteryVoltage = float
+	case m.soc >= 95:
+		m.chargingState = gorenogymodbus.BoostChargingMode
+		m.batteryVoltage = boost
+	default:
+		m.chargingState = gorenogymodbus.MPPTChargingMode
+		m.batteryVoltage = math.Min(restVoltage+0.8, boost)
+	}
+	m.chargeCurrent = m.chargePower / m.batteryVoltage
+	m.panelCurrent = 0
+	if m.panelVoltage > 0 {
+		m.panelCurrent = m.chargePower / 0.96 / m.panelVoltage
+	}
+
+	wasFull := m.soc >= 100
+	m.soc += (m.chargeCurrent - m.loadCurrent) * hours / capacity * 100
+	m.soc = math.Max(0, math.Min(100, m.soc))
+	if !wasFull && m.soc >= 100 {
+		m.fullCharges++
+	}
+
+	// low voltage disconnect
+	if m.loadOn && m.soc < 10 {
+		m.loadOn = false
+		m.overDischarge++
+	}
+
+	t := &m.today
+	t.minVoltage = math.Min(t.minVoltage, m.batteryVoltage)
+	t.maxVoltage = math.Max(t.maxVoltage, m.batteryVoltage)
+	t.maxChargeCurrent = math.Max(t.maxChargeCurrent, m.chargeCurrent)
+	t.maxLoadCurrent = math.Max(t.maxLoadCurrent, m.loadCurrent)
+	t.maxChargePower = math.Max(t.maxChargePower, m.chargePower)
+	t.maxLoadPower = math.Max(t.maxLoadPower, m.loadPower)
+	t.chargeAmpHours += m.chargeCurrent * hours
+	t.dischargeAmpHours += m.loadCurrent * hours
+	t.generationKWh += m.chargePower * hours / 1000
+	t.consumptionKWh += m.loadPower * hours / 1000
+
+	m.totalChargeAh += m.chargeCurrent * hours
+	m.totalLoadAh += m.loadCurrent * hours
+	m.totalGenKWh += m.chargePower * hours / 1000
+	m.totalConsKWh += m.loadPower * hours / 1000
+}
+
+// newDay starts a new controller day at sunrise like the controller does.
+func (m *Model) newDay() {
+	m.history = append([]dayTotals{m.today}, m.history...)
+	if len(m.history) >= gorenogymodbus.MaximumHistoryDays {
+		m.history = m.history[:gorenogymodbus.MaximumHistoryDays-1]
+	}
+	m.today = dayTotals{minVoltage: m.batteryVoltage, maxVoltage: m.batteryVoltage}
+	m.operatingDays++
+}
+
+func (m *Model) reading() *gorenogymodbus.DynamicControllerInformation {
+	active := m.activeFaults()
+	var faults []string
+	for bit := 16; bit < 32; bit++ {
+		fault, ok := gorenogymodbus.ControllerFaultsMap[bit]
+		if ok && active&(1<<uint(bit)) != 0 {
+			faults = append(faults, fault.String())
+		}
+	}
+
+	t := m.today
+	return &gorenogymodbus.DynamicControllerInformation{
+		BatteryCapacitySOC:                  int(math.Round(m.soc)),
+		BatteryVoltage:                      round(m.batteryVoltage, 1),
+		ChargingCurrent:                     round(m.chargeCurrent, 2),
+		ControllerTemperature:               int(25 + m.chargePower/20),
+		BatteryTemperature:                  20,
+		StreetLightLoadVoltage:              round(m.batteryVoltage*boolFloat(m.loadOn), 1),
+		StreetLightLoadCurrent:              round(m.loadCurrent, 2),
+		StreetLightLoadPower:                round(m.loadPower, 0),
+		SolarPanelVoltage:                   round(m.panelVoltage, 1),
+		SolarPanelCurrent:                   round(m.panelCurrent, 2),
+		ChargingPower:                       round(m.chargePower, 0),
+		BatteryMinimumVoltageCurrentDay:     round(t.minVoltage, 1),
+		BatteryMaximumVoltageCurrentDay:     round(t.maxVoltage, 1),
+		MaximumChargingCurrentCurrentDay:    round(t.maxChargeCurrent, 2),
+		MaximumDischargingCurrentCurrentDay: round(t.maxLoadCurrent, 2),
+		MaximumChargingPowerCurrentDay:      round(t.maxChargePower, 0),
+		MaximumDischargingPowerCurrentDay:   round(t.maxLoadPower, 0),
+		ChargingAmpHoursCurrentDay:          round(t.chargeAmpHours, 0),
+		DischargingAmpHoursCurrentDay:       round(t.dischargeAmpHours, 0),
+		PowerGenerationCurrentDay:           round(t.generationKWh, 4),
+		PowerConsumptionCurrentDay:          round(t.consumptionKWh, 4),
+		TotalOperatingDays:                  m.operatingDays,
+		TotalBatteryOverDischarges:          m.overDischarge,
+		TotalBatteryFullCharges:             m.fullCharges,
+		TotalChargingAmpHours:               round(m.totalChargeAh, 0),
+		TotalDischargingAmpHours:            round(m.totalLoadAh, 0),
+		CumulativePowerGeneration:           round(m.totalGenKWh, 4),
+		CumulativePowerConsumption:          round(m.totalConsKWh, 4),
+		StreetLightStatus:                   m.loadOn,
+		StreetLightBrightness:               int(100 * boolFloat(m.loadOn)),
+		ChargingState:                       m.chargingState.String(),
+		ControllerFaults:                    faults,
+	}
+}
+
+func (m *Model) dailyTotals(day int) *gorenogymodbus.DailyTotals {
+	t := m.today
+	if day > 0 {
+		t = dayTotals{}
+		if day <= len(m.history) {
+			t = m.history[day-1]
+		}
+	}
+
+	return &gorenogymodbus.DailyTotals{
+		BatteryMinimumVoltage:     round(t.minVoltage, 1),
+		BatteryMaximumVoltage:     round(t.maxVoltage, 1),
+		MaximumChargingCurrent:    round(t.maxChargeCurrent, 2),
+		MaximumDischargingCurrent: round(t.maxLoadCurrent, 2),
+		MaximumChargingPower:      round(t.maxChargePower, 0),
+		MaximumDischargingPower:   round(t.maxLoadPower, 0),
+		ChargingAmpHours:          round(t.chargeAmpHours, 0),
+		DischargingAmpHours:       round(t.dischargeAmpHours, 0),
+		PowerGeneration:           round(t.generationKWh, 4),
+		PowerConsumption:          round(t.consumptionKWh, 4),
+	}
+}
+
+// ReadHoldingRegisters returns the registers of one of the product information,
+// dynamic, settings or history blocks.
+func (m *Model) ReadHoldingRegisters(address, quantity uint16) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.advance(m.config.Clock())
+	if quantity == 0 || quantity > 125 {
+		return nil, ExceptionIllegalDataValue
+	}
+	end := uint32(address) + uint32(quantity)
+
+	var block []byte
+	var start uint16
+	var err error
+	switch {
+	case address >= productAddress && end <= uint32(productAddress+productQuantity):
+		start = productAddress
+		block, err = m.product.Synthesize()
+	case address >= dynamicAddress && end <= uint32(dynamicAddress+dynamicQuantity):
+		start = dynamicAddress
+		block, err = m.reading().Synthesize()
+	case address >= settingsAddress && end <= uint32(settingsEnd)+1:
+		start = settingsAddress
+		block, err = m.settings.Synthesize()
+		block = block[:len(block)-2]
+	case address == loadModeAddress && quantity == 1:
+		start = loadModeAddress
+		block = binary.BigEndian.AppendUint16(nil, uint16(m.settings.LoadWorkingMode))
+	case address >= historyAddress && end <= uint32(historyAddress)+uint32(historyQuantity)*gorenogymodbus.MaximumHistoryDays:
+		start = historyAddress
+		for day := 0; day < gorenogymodbus.MaximumHistoryDays; day++ {
+			b, err := m.dailyTotals(day).Synthesize()
+			if err != nil {
+				return nil, ExceptionServerDeviceFailure
+			}
+			block = append(block, b...)
+		}
+	default:
+		return nil, ExceptionIllegalDataAddress
+	}
+	if err != nil {
+		return nil, ExceptionServerDeviceFailure
+	}
+
+	offset := int(address-start) * 2
+	return block[offset : offset+int(quantity)*2], nil
+}
+
+// WriteRegisters writes the load command register or settings registers.
+func (m *Model) WriteRegisters(address uint16, values []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.advance(m.config.Clock())
+	quantity := uint16(len(values) / 2)
+	if quantity == 0 || len(values)%2 != 0 {
+		return ExceptionIllegalDataValue
+	}
+	end := uint32(address) + uint32(quantity)
+
+	switch {
+	case address == loadCommand && quantity == 1:
+		v := binary.BigEndian.Uint16(values)
+		if v > 1 {
+			return ExceptionIllegalDataValue
+		}
+		m.loadOn = v == 1
+		return nil
+	case address == loadModeAddress && quantity == 1:
+		v := binary.BigEndian.Uint16(values)
+		if v > 0x11 {
+			return ExceptionIllegalDataValue
+		}
+		m.settings.LoadWorkingMode = int(v)
+		return nil
+	case address >= settingsAddress && end <= uint32(settingsEnd)+1:
+		block, err := m.settings.Synthesize()
+		if err != nil {
+			return ExceptionServerDeviceFailure
+		}
+
+		offset := int(address-settingsAddress) * 2
+		recognized := block[3]
+		copy(block[offset:], values)
+		block[3] = recognized // read only
+
+		settings, err := gorenogymodbus.ParseSettings(block)
+		if err != nil {
+			return ExceptionServerDeviceFailure
+		}
+		if settings.Validate() != nil {
+			return ExceptionIllegalDataValue
+		}
+		m.settings = *settings
+		return nil
+	default:
+		return ExceptionIllegalDataAddress
+	}
+}
+
+func round(f float64, places int32) decimal.Decimal {
+	return decimal.NewFromFloat(f).Round(places)
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
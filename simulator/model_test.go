@@ -0,0 +1,135 @@
+package simulator_test
+
+import (
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/simulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newModel(t *testing.T, start time.Time, config simulator.ModelConfig) (*simulator.Model, *fakeClock) {
+	clock := &fakeClock{now: start}
+	config.Clock = clock.Now
+	return simulator.NewModel(config), clock
+}
+
+func TestModelSunCurve(t *testing.T) {
+	night := time.Date(2023, 8, 10, 1, 0, 0, 0, time.UTC)
+	m, clock := newModel(t, night, simulator.ModelConfig{LoadOn: true})
+
+	dci := m.Reading()
+	assert.Equal(t, gorenogymodbus.ChargingDeactivated.String(), dci.ChargingState)
+	assert.True(t, dci.ChargingPower.IsZero())
+	assert.Equal(t, 60, dci.BatteryCapacitySOC)
+
+	// 4 hours of a 20W load from a 100Ah battery
+	clock.now = night.Add(4 * time.Hour)
+	m.Sync()
+	dci = m.Reading()
+	assert.Equal(t, 54, dci.BatteryCapacitySOC)
+	assert.Equal(t, "0.08", dci.PowerConsumptionCurrentDay.String())
+
+	clock.now = time.Date(2023, 8, 10, 9, 0, 0, 0, time.UTC)
+	m.Sync()
+	dci = m.Reading()
+	assert.Equal(t, gorenogymodbus.MPPTChargingMode.String(), dci.ChargingState)
+	assert.Equal(t, "239", dci.ChargingPower.String())
+	assert.Equal(t, 2, dci.TotalOperatingDays)
+	assert.Empty(t, dci.Validate())
+}
+
+func TestModelFullCharge(t *testing.T) {
+	start := time.Date(2023, 8, 10, 7, 0, 0, 0, time.UTC)
+	m, clock := newModel(t, start, simulator.ModelConfig{InitialSOC: 90})
+
+	clock.now = start.Add(8 * time.Hour)
+	m.Sync()
+	dci := m.Reading()
+	assert.Equal(t, 100, dci.BatteryCapacitySOC)
+	assert.Equal(t, gorenogymodbus.FloatingChargingMode.String(), dci.ChargingState)
+	assert.Equal(t, 1, dci.TotalBatteryFullCharges)
+}
+
+func TestModelHistory(t *testing.T) {
+	start := time.Date(2023, 8, 10, 7, 0, 0, 0, time.UTC)
+	m, clock := newModel(t, start, simulator.ModelConfig{})
+
+	clock.now = start.Add(24 * time.Hour)
+	m.Sync()
+
+	today, err := m.ReadHoldingRegisters(0xF000, 10)
+	require.NoError(t, err)
+	yesterday, err := m.ReadHoldingRegisters(0xF00A, 10)
+	require.NoError(t, err)
+
+	dt, err := gorenogymodbus.ParseDailyHistory(yesterday)
+	require.NoError(t, err)
+	assert.True(t, dt.PowerGeneration.GreaterThan(dt.PowerConsumption))
+
+	dci, err := m.ReadHoldingRegisters(0x10B, 10)
+	require.NoError(t, err)
+	assert.Equal(t, dci, today)
+}
+
+func TestModelRegisters(t *testing.T) {
+	m, _ := newModel(t, time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC), simulator.ModelConfig{})
+
+	tests := []struct {
+		name     string
+		address  uint16
+		quantity uint16
+		err      error
+	}{
+		{name: "product information", address: 0x0A, quantity: 17},
+		{name: "dynamic", address: 0x100, quantity: 35},
+		{name: "dynamic range", address: 0x107, quantity: 3},
+		{name: "settings", address: 0xE002, quantity: 19},
+		{name: "load working mode", address: 0xE01D, quantity: 1},
+		{name: "last day of history", address: 0xF122, quantity: 10},
+		{name: "past dynamic block", address: 0x100, quantity: 36, err: simulator.ExceptionIllegalDataAddress},
+		{name: "unmapped", address: 0x2000, quantity: 1, err: simulator.ExceptionIllegalDataAddress},
+		{name: "past history", address: 0xF12C, quantity: 10, err: simulator.ExceptionIllegalDataAddress},
+		{name: "zero quantity", address: 0x100, quantity: 0, err: simulator.ExceptionIllegalDataValue},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := m.ReadHoldingRegisters(tc.address, tc.quantity)
+			if tc.err != nil {
+				assert.Equal(t, tc.err, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, res, int(tc.quantity)*2)
+		})
+	}
+}
+
+func TestModelWrites(t *testing.T) {
+	m, _ := newModel(t, time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC), simulator.ModelConfig{})
+
+	require.NoError(t, m.WriteRegisters(0x10A, []byte{0x00, 0x01}))
+	assert.True(t, m.Reading().StreetLightStatus)
+	assert.Equal(t, simulator.ExceptionIllegalDataValue, m.WriteRegisters(0x10A, []byte{0x00, 0x02}))
+
+	// boost charging voltage, 14.2V
+	require.NoError(t, m.WriteRegisters(0xE008, []byte{0x00, 0x8E}))
+	b, err := m.ReadHoldingRegisters(0xE002, 19)
+	require.NoError(t, err)
+	settings, err := gorenogymodbus.ParseSettings(append(b, 0x00, 0x0F))
+	require.NoError(t, err)
+	assert.Equal(t, "14.2", settings.BoostChargingVoltage.String())
+
+	// battery type past lithium
+	assert.Equal(t, simulator.ExceptionIllegalDataValue, m.WriteRegisters(0xE004, []byte{0x00, 0x05}))
+	assert.Equal(t, simulator.ExceptionIllegalDataAddress, m.WriteRegisters(0x100, []byte{0x00, 0x01}))
+}
@@ -0,0 +1,56 @@
+package simulator
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/creack/pty"
+	"golang.org/x/term"
+)
+
+// PTY is a pseudo-terminal pair. The simulator serves on the master side and
+// clients open the slave device by Path as if it were a serial port.
+type PTY struct {
+	master *os.File
+	slave  *os.File
+}
+
+// OpenPTY opens a pseudo-terminal with its slave side in raw mode.
+func OpenPTY() (*PTY, error) {
+	master, slave, err := pty.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pseudo-terminal: %w", err)
+	}
+
+	if _, err := term.MakeRaw(int(slave.Fd())); err != nil {
+		master.Close()
+		slave.Close()
+		return nil, fmt.Errorf("failed to set pseudo-terminal raw: %w", err)
+	}
+
+	return &PTY{master: master, slave: slave}, nil
+}
+
+// Path is the device path of the slave side to point clients at.
+func (p *PTY) Path() string {
+	return p.slave.Name()
+}
+
+func (p *PTY) Read(b []byte) (int, error) {
+	return p.master.Read(b)
+}
+
+func (p *PTY) Write(b []byte) (int, error) {
+	return p.master.Write(b)
+}
+
+func (p *PTY) Close() error {
+	slaveErr := p.slave.Close()
+	if err := p.master.Close(); err != nil {
+		return fmt.Errorf("failed to close pseudo-terminal: %w", err)
+	}
+	if slaveErr != nil {
+		return fmt.Errorf("failed to close pseudo-terminal: %w", slaveErr)
+	}
+	return nil
+}
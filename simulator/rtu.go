@@ -0,0 +1,245 @@
+package simulator
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+const (
+	funcReadHoldingRegisters   byte = 0x03
+	funcWriteSingleRegister    byte = 0x06
+	funcWriteMultipleRegisters byte = 0x10
+
+	// frameGap discards a partial frame when no bytes follow within it. Modbus
+	// requires 3.5 character times, this is generous for pseudo-terminals.
+	frameGap = 50 * time.Millisecond
+)
+
+// Exception is a Modbus exception code returned to the master.
+type Exception byte
+
+const (
+	ExceptionIllegalFunction     Exception = 0x01
+	ExceptionIllegalDataAddress  Exception = 0x02
+	ExceptionIllegalDataValue    Exception = 0x03
+	ExceptionServerDeviceFailure Exception = 0x04
+)
+
+func (e Exception) Error() string {
+	switch e {
+	case ExceptionIllegalFunction:
+		return "illegal function"
+	case ExceptionIllegalDataAddress:
+		return "illegal data address"
+	case ExceptionIllegalDataValue:
+		return "illegal data value"
+	case ExceptionServerDeviceFailure:
+		return "server device failure"
+	default:
+		return fmt.Sprintf("exception 0x%02x", byte(e))
+	}
+}
+
+// Device is the register map a Server answers requests from.
+type Device interface {
+	ReadHoldingRegisters(address, quantity uint16) ([]byte, error)
+	WriteRegisters(address uint16, values []byte) error
+}
+
+// Server is a Modbus RTU slave answering requests addressed to SlaveID.
+type Server struct {
+	SlaveID  byte
+	Device   Device
+	Injector *Injector // optional
+	Logger   *log.Logger
+}
+
+func NewServer(slaveID byte, device Device) *Server {
+	return &Server{SlaveID: slaveID, Device: device}
+}
+
+// Serve answers requests read from rw until ctx is done or rw fails.
+func (s *Server) Serve(ctx context.Context, rw io.ReadWriter) error {
+	chunks := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := rw.Read(buf)
+			if n > 0 {
+				chunk := append([]byte(nil), buf[:n]...)
+				select {
+				case chunks <- chunk:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	var pending []byte
+	gap := time.NewTimer(frameGap)
+	defer gap.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read request: %w", err)
+		case <-gap.C:
+			if len(pending) > 0 {
+				s.logf("discarding incomplete frame % x", pending)
+				pending = nil
+			}
+		case chunk := <-chunks:
+			pending = append(pending, chunk...)
+			gap.Reset(frameGap)
+
+			for {
+				frame, rest, ok := nextFrame(pending)
+				if !ok {
+					break
+				}
+				pending = rest
+				if frame == nil {
+					continue
+				}
+
+				response := s.Handle(frame)
+				var delay time.Duration
+				if s.Injector != nil {
+					response, delay = s.Injector.Apply(frame, response)
+				}
+				if response == nil {
+					continue
+				}
+				if delay > 0 {
+					select {
+					case <-time.After(delay):
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+				if _, err := rw.Write(response); err != nil {
+					return fmt.Errorf("failed to write response: %w", err)
+				}
+			}
+		}
+	}
+}
+
+// nextFrame splits the first complete frame off buf. A nil frame with ok set
+// means a byte was dropped to resynchronise after a checksum mismatch.
+func nextFrame(buf []byte) (frame []byte, rest []byte, ok bool) {
+	if len(buf) < 2 {
+		return nil, buf, false
+	}
+
+	length := 8
+	if buf[1] == funcWriteMultipleRegisters {
+		if len(buf) < 7 {
+			return nil, buf, false
+		}
+		length = 9 + int(buf[6])
+	}
+	if len(buf) < length {
+		return nil, buf, false
+	}
+
+	frame = buf[:length]
+	if crc16(frame[:length-2]) != binary.LittleEndian.Uint16(frame[length-2:]) {
+		return nil, buf[1:], true
+	}
+
+	return frame, buf[length:], true
+}
+
+// Handle returns the response to a single request frame including its checksum,
+// or nil when the request is not addressed to this slave.
+func (s *Server) Handle(frame []byte) []byte {
+	if len(frame) < 4 || frame[0] != s.SlaveID {
+		return nil
+	}
+
+	function := frame[1]
+	pdu, err := s.handle(function, frame[2:len(frame)-2])
+	if err != nil {
+		exception, ok := err.(Exception)
+		if !ok {
+			exception = ExceptionServerDeviceFailure
+		}
+		s.logf("function 0x%02x failed: %v", function, err)
+		function |= 0x80
+		pdu = []byte{byte(exception)}
+	}
+
+	response := append([]byte{s.SlaveID, function}, pdu...)
+	return binary.LittleEndian.AppendUint16(response, crc16(response))
+}
+
+func (s *Server) handle(function byte, data []byte) ([]byte, error) {
+	switch function {
+	case funcReadHoldingRegisters:
+		if len(data) != 4 {
+			return nil, ExceptionIllegalDataValue
+		}
+		quantity := binary.BigEndian.Uint16(data[2:4])
+		values, err := s.Device.ReadHoldingRegisters(binary.BigEndian.Uint16(data[0:2]), quantity)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{byte(len(values))}, values...), nil
+	case funcWriteSingleRegister:
+		if len(data) != 4 {
+			return nil, ExceptionIllegalDataValue
+		}
+		if err := s.Device.WriteRegisters(binary.BigEndian.Uint16(data[0:2]), data[2:4]); err != nil {
+			return nil, err
+		}
+		return data, nil
+	case funcWriteMultipleRegisters:
+		if len(data) < 5 || int(data[4]) != len(data)-5 || int(binary.BigEndian.Uint16(data[2:4]))*2 != len(data)-5 {
+			return nil, ExceptionIllegalDataValue
+		}
+		if err := s.Device.WriteRegisters(binary.BigEndian.Uint16(data[0:2]), data[5:]); err != nil {
+			return nil, err
+		}
+		return data[:4], nil
+	default:
+		return nil, ExceptionIllegalFunction
+	}
+}
+
+func (s *Server) logf(format string, v ...interface{}) {
+	if s.Logger != nil {
+		s.Logger.Printf(format, v...)
+	}
+}
+
+// crc16 is the Modbus CRC-16 (polynomial 0xA001, initial value 0xFFFF).
+func crc16(b []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, v := range b {
+		crc ^= uint16(v)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
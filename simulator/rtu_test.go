@@ -0,0 +1,121 @@
+package simulator_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/simulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type registers map[uint16][]byte
+
+func (r registers) ReadHoldingRegisters(address, quantity uint16) ([]byte, error) {
+	b, ok := r[address]
+	if !ok || len(b) != int(quantity)*2 {
+		return nil, simulator.ExceptionIllegalDataAddress
+	}
+	return b, nil
+}
+
+func (r registers) WriteRegisters(address uint16, values []byte) error {
+	if _, ok := r[address]; !ok {
+		return simulator.ExceptionIllegalDataAddress
+	}
+	r[address] = values
+	return nil
+}
+
+func TestServerHandle(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  []byte
+		response []byte
+	}{
+		{
+			name:     "read holding registers",
+			request:  []byte{0x01, 0x03, 0x01, 0x00, 0x00, 0x01, 0x85, 0xf6},
+			response: []byte{0x01, 0x03, 0x02, 0x00, 0x64, 0xb9, 0xaf},
+		},
+		{
+			name:     "write single register",
+			request:  []byte{0x01, 0x06, 0x01, 0x0a, 0x00, 0x01, 0x69, 0xf4},
+			response: []byte{0x01, 0x06, 0x01, 0x0a, 0x00, 0x01, 0x69, 0xf4},
+		},
+		{
+			name:     "write multiple registers",
+			request:  []byte{0x01, 0x10, 0x01, 0x0a, 0x00, 0x01, 0x02, 0x00, 0x00, 0xb6, 0x3a},
+			response: []byte{0x01, 0x10, 0x01, 0x0a, 0x00, 0x01, 0x20, 0x37},
+		},
+		{
+			name:     "illegal data address",
+			request:  []byte{0x01, 0x03, 0x20, 0x00, 0x00, 0x01, 0x8f, 0xca},
+			response: []byte{0x01, 0x83, 0x02, 0xc0, 0xf1},
+		},
+		{
+			name:     "illegal function",
+			request:  []byte{0x01, 0x04, 0x01, 0x00, 0x00, 0x01, 0x30, 0x36},
+			response: []byte{0x01, 0x84, 0x01, 0x82, 0xc0},
+		},
+		{
+			name:    "other slave",
+			request: []byte{0x02, 0x03, 0x01, 0x00, 0x00, 0x01, 0x85, 0xc5},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := simulator.NewServer(1, registers{0x100: {0x00, 0x64}, 0x10A: {0x00, 0x00}})
+			assert.Equal(t, tc.response, s.Handle(tc.request))
+		})
+	}
+}
+
+func TestServerPTY(t *testing.T) {
+	start := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	model := simulator.NewModel(simulator.ModelConfig{Clock: func() time.Time { return start }})
+
+	p, err := simulator.OpenPTY()
+	require.NoError(t, err)
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go simulator.NewServer(1, model).Serve(ctx, p) //nolint:errcheck
+
+	mc, err := gorenogymodbus.NewModbusClient(log.New(io.Discard, "", 0), p.Path(), time.Minute)
+	require.NoError(t, err)
+
+	res, err := mc.ReadData()
+	require.NoError(t, err)
+	dci, err := gorenogymodbus.Parse(res)
+	require.NoError(t, err)
+	assert.Equal(t, gorenogymodbus.MPPTChargingMode.String(), dci.ChargingState)
+	assert.False(t, dci.StreetLightStatus)
+
+	require.NoError(t, mc.SetLoad(true))
+	res, err = mc.ReadData()
+	require.NoError(t, err)
+	dci, err = gorenogymodbus.Parse(res)
+	require.NoError(t, err)
+	assert.True(t, dci.StreetLightStatus)
+
+	pi, err := mc.ReadProductInformation()
+	require.NoError(t, err)
+	assert.Equal(t, "RNG-CTRL-RVR40", pi.Model)
+
+	settings, err := mc.ReadSettings()
+	require.NoError(t, err)
+	assert.Equal(t, gorenogymodbus.SealedBattery.String(), settings.BatteryType)
+
+	_, err = mc.ReadDailyHistory(29)
+	require.NoError(t, err)
+
+	_, err = mc.Client.ReadHoldingRegisters(0x2000, 1)
+	assert.ErrorContains(t, err, "exception '2'")
+}
@@ -0,0 +1,163 @@
+package gorenogymodbus
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+type ViolationKind int
+
+const (
+	OutOfRange ViolationKind = iota
+	Inconsistent
+	CounterDecreased
+)
+
+func (vk ViolationKind) String() string {
+	switch vk {
+	case OutOfRange:
+		return "out of range"
+	case Inconsistent:
+		return "inconsistent"
+	case CounterDecreased:
+		return "counter decreased"
+	default:
+		return "unknown"
+	}
+}
+
+// Violation is a failed plausibility check. Field is the json name of the field
+// that failed the check.
+type Violation struct {
+	Field   string        `json:"field"`
+	Kind    ViolationKind `json:"kind"`
+	Message string        `json:"message"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s: %s", v.Field, v.Kind, v.Message)
+}
+
+var (
+	// powerTolerance is the relative difference allowed between a reported power and V×I,
+	// with powerToleranceWatts as a floor for low power readings.
+	powerTolerance      = decimal.NewFromFloat(0.1)
+	powerToleranceWatts = decimal.NewFromInt(5)
+)
+
+type decimalRange struct {
+	name     string
+	value    decimal.Decimal
+	min, max float64
+}
+
+type intRange struct {
+	name     string
+	value    int
+	min, max int
+}
+
+// Validate checks the physical ranges of fields and the consistency between them.
+// Fields absent from a partial reading are not checked. An empty result means the
+// reading is plausible.
+func (dci *DynamicControllerInformation) Validate() []Violation {
+	var violations []Violation
+
+	for _, r := range []intRange{
+		{"battery_capacity_soc", dci.BatteryCapacitySOC, 0, 100},
+		{"controller_temperature", dci.ControllerTemperature, -40, 100},
+		{"battery_temperature", dci.BatteryTemperature, -40, 100},
+		{"street_light_brightness", dci.StreetLightBrightness, 0, 100},
+	} {
+		if dci.Has(r.name) && (r.value < r.min || r.value > r.max) {
+			violations = append(violations, Violation{r.name, OutOfRange, fmt.Sprintf("%d is outside of %d to %d", r.value, r.min, r.max)})
+		}
+	}
+
+	for _, r := range []decimalRange{
+		{"battery_voltage", dci.BatteryVoltage, 0, 80},
+		{"charging_current", dci.ChargingCurrent, 0, 100},
+		{"street_light_load_voltage", dci.StreetLightLoadVoltage, 0, 80},
+		{"street_light_load_current", dci.StreetLightLoadCurrent, 0, 100},
+		{"street_light_load_power", dci.StreetLightLoadPower, 0, 5000},
+		{"solar_panel_voltage", dci.SolarPanelVoltage, 0, 250},
+		{"solar_panel_current", dci.SolarPanelCurrent, 0, 100},
+		{"charging_power", dci.ChargingPower, 0, 5000},
+	} {
+		if dci.Has(r.name) && (r.value.LessThan(decimal.NewFromFloat(r.min)) || r.value.GreaterThan(decimal.NewFromFloat(r.max))) {
+			violations = append(violations, Violation{r.name, OutOfRange, fmt.Sprintf("%s is outside of %g to %g", r.value, r.min, r.max)})
+		}
+	}
+
+	if dci.Has("charging_state") && chargingStateFromString(dci.ChargingState) < 0 {
+		violations = append(violations, Violation{"charging_state", OutOfRange, fmt.Sprintf("unknown charging state %q", dci.ChargingState)})
+	}
+
+	if dci.Has("charging_power") && dci.Has("battery_voltage") && dci.Has("charging_current") &&
+		!powerMatches(dci.ChargingPower, dci.BatteryVoltage, dci.ChargingCurrent) {
+		violations = append(violations, Violation{"charging_power", Inconsistent, fmt.Sprintf("%s W does not match %s V × %s A", dci.ChargingPower, dci.BatteryVoltage, dci.ChargingCurrent)})
+	}
+
+	if dci.Has("street_light_load_power") && dci.Has("street_light_load_voltage") && dci.Has("street_light_load_current") &&
+		!powerMatches(dci.StreetLightLoadPower, dci.StreetLightLoadVoltage, dci.StreetLightLoadCurrent) {
+		violations = append(violations, Violation{"street_light_load_power", Inconsistent, fmt.Sprintf("%s W does not match %s V × %s A", dci.StreetLightLoadPower, dci.StreetLightLoadVoltage, dci.StreetLightLoadCurrent)})
+	}
+
+	for _, c := range []struct {
+		maxName, name string
+		max, value    decimal.Decimal
+	}{
+		{"battery_maximum_voltage_current_day", "battery_voltage", dci.BatteryMaximumVoltageCurrentDay, dci.BatteryVoltage},
+		{"maximum_charging_current_current_day", "charging_current", dci.MaximumChargingCurrentCurrentDay, dci.ChargingCurrent},
+		{"maximum_discharging_current_current_day", "street_light_load_current", dci.MaximumDischargingCurrentCurrentDay, dci.StreetLightLoadCurrent},
+		{"maximum_charging_power_current_day", "charging_power", dci.MaximumChargingPowerCurrentDay, dci.ChargingPower},
+		{"maximum_discharging_power_current_day", "street_light_load_power", dci.MaximumDischargingPowerCurrentDay, dci.StreetLightLoadPower},
+	} {
+		if dci.Has(c.maxName) && dci.Has(c.name) && c.max.LessThan(c.value) {
+			violations = append(violations, Violation{c.maxName, Inconsistent, fmt.Sprintf("daily maximum %s is below current %s %s", c.max, c.name, c.value)})
+		}
+	}
+
+	// the daily minimum reads zero until the controller has recorded one
+	if dci.Has("battery_minimum_voltage_current_day") && dci.Has("battery_voltage") &&
+		dci.BatteryMinimumVoltageCurrentDay.IsPositive() && dci.BatteryMinimumVoltageCurrentDay.GreaterThan(dci.BatteryVoltage) {
+		violations = append(violations, Violation{"battery_minimum_voltage_current_day", Inconsistent, fmt.Sprintf("daily minimum %s is above current battery_voltage %s", dci.BatteryMinimumVoltageCurrentDay, dci.BatteryVoltage)})
+	}
+
+	return violations
+}
+
+// ValidateAgainst runs Validate and additionally checks that the cumulative counters
+// have not decreased since previous, an earlier reading from the same controller.
+func (dci *DynamicControllerInformation) ValidateAgainst(previous *DynamicControllerInformation) []Violation {
+	violations := dci.Validate()
+	if previous == nil {
+		return violations
+	}
+
+	for _, c := range []struct {
+		name            string
+		value, previous decimal.Decimal
+	}{
+		{"total_operating_days", decimal.NewFromInt(int64(dci.TotalOperatingDays)), decimal.NewFromInt(int64(previous.TotalOperatingDays))},
+		{"total_battery_over_discharges", decimal.NewFromInt(int64(dci.TotalBatteryOverDischarges)), decimal.NewFromInt(int64(previous.TotalBatteryOverDischarges))},
+		{"total_battery_full_charges", decimal.NewFromInt(int64(dci.TotalBatteryFullCharges)), decimal.NewFromInt(int64(previous.TotalBatteryFullCharges))},
+		{"total_charging_amp_hours", dci.TotalChargingAmpHours, previous.TotalChargingAmpHours},
+		{"total_discharging_amp_hours", dci.TotalDischargingAmpHours, previous.TotalDischargingAmpHours},
+		{"cumulative_power_generation", dci.CumulativePowerGeneration, previous.CumulativePowerGeneration},
+		{"cumulative_power_consumption", dci.CumulativePowerConsumption, previous.CumulativePowerConsumption},
+	} {
+		if dci.Has(c.name) && previous.Has(c.name) && c.value.LessThan(c.previous) {
+			violations = append(violations, Violation{c.name, CounterDecreased, fmt.Sprintf("decreased from %s to %s", c.previous, c.value)})
+		}
+	}
+
+	return violations
+}
+
+func powerMatches(power decimal.Decimal, voltage decimal.Decimal, current decimal.Decimal) bool {
+	expected := voltage.Mul(current)
+	tolerance := decimal.Max(expected.Mul(powerTolerance), powerToleranceWatts)
+	return power.Sub(expected).Abs().LessThanOrEqual(tolerance)
+}
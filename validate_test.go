@@ -0,0 +1,123 @@
+package gorenogymodbus_test
+
+import (
+	"testing"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func plausibleReading() gorenogymodbus.DynamicControllerInformation {
+	return gorenogymodbus.DynamicControllerInformation{
+		BatteryCapacitySOC:                  80,
+		BatteryVoltage:                      decimal.NewFromFloat(13.2),
+		ChargingCurrent:                     decimal.NewFromFloat(5),
+		ControllerTemperature:               25,
+		BatteryTemperature:                  20,
+		StreetLightLoadVoltage:              decimal.NewFromFloat(13.2),
+		StreetLightLoadCurrent:              decimal.NewFromFloat(1),
+		StreetLightLoadPower:                decimal.NewFromFloat(13),
+		SolarPanelVoltage:                   decimal.NewFromFloat(18.5),
+		SolarPanelCurrent:                   decimal.NewFromFloat(3.6),
+		ChargingPower:                       decimal.NewFromFloat(66),
+		BatteryMinimumVoltageCurrentDay:     decimal.NewFromFloat(12.8),
+		BatteryMaximumVoltageCurrentDay:     decimal.NewFromFloat(13.6),
+		MaximumChargingCurrentCurrentDay:    decimal.NewFromFloat(6),
+		MaximumDischargingCurrentCurrentDay: decimal.NewFromFloat(1),
+		MaximumChargingPowerCurrentDay:      decimal.NewFromFloat(80),
+		MaximumDischargingPowerCurrentDay:   decimal.NewFromFloat(13),
+		TotalOperatingDays:                  12,
+		TotalChargingAmpHours:               decimal.NewFromFloat(100),
+		CumulativePowerGeneration:           decimal.NewFromFloat(1.5),
+		ChargingState:                       gorenogymodbus.MPPTChargingMode.String(),
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Modify     func(dci *gorenogymodbus.DynamicControllerInformation)
+		Violations map[string]gorenogymodbus.ViolationKind
+	}{
+		{
+			Name:       "plausible",
+			Modify:     func(dci *gorenogymodbus.DynamicControllerInformation) {},
+			Violations: map[string]gorenogymodbus.ViolationKind{},
+		},
+		{
+			Name: "rebooted controller garbage",
+			Modify: func(dci *gorenogymodbus.DynamicControllerInformation) {
+				dci.BatteryCapacitySOC = 655
+				dci.BatteryVoltage = decimal.NewFromFloat(6553.5)
+				dci.BatteryMaximumVoltageCurrentDay = decimal.NewFromFloat(6553.5)
+			},
+			Violations: map[string]gorenogymodbus.ViolationKind{
+				"battery_capacity_soc": gorenogymodbus.OutOfRange,
+				"battery_voltage":      gorenogymodbus.OutOfRange,
+				"charging_power":       gorenogymodbus.Inconsistent,
+			},
+		},
+		{
+			Name: "daily maximum below current value",
+			Modify: func(dci *gorenogymodbus.DynamicControllerInformation) {
+				dci.MaximumChargingCurrentCurrentDay = decimal.NewFromFloat(4)
+				dci.BatteryMinimumVoltageCurrentDay = decimal.NewFromFloat(13.3)
+			},
+			Violations: map[string]gorenogymodbus.ViolationKind{
+				"maximum_charging_current_current_day": gorenogymodbus.Inconsistent,
+				"battery_minimum_voltage_current_day":  gorenogymodbus.Inconsistent,
+			},
+		},
+		{
+			Name: "unknown charging state",
+			Modify: func(dci *gorenogymodbus.DynamicControllerInformation) {
+				dci.ChargingState = gorenogymodbus.ChargingState(9).String()
+			},
+			Violations: map[string]gorenogymodbus.ViolationKind{
+				"charging_state": gorenogymodbus.OutOfRange,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dci := plausibleReading()
+			tc.Modify(&dci)
+
+			result := map[string]gorenogymodbus.ViolationKind{}
+			for _, v := range dci.Validate() {
+				result[v.Field] = v.Kind
+			}
+			assert.Equal(t, tc.Violations, result)
+		})
+	}
+}
+
+func TestValidateAgainst(t *testing.T) {
+	previous := plausibleReading()
+	current := plausibleReading()
+	current.TotalChargingAmpHours = decimal.NewFromFloat(99)
+	current.TotalOperatingDays = 13
+
+	violations := current.ValidateAgainst(&previous)
+	assert.Len(t, violations, 1)
+	assert.Equal(t, "total_charging_amp_hours", violations[0].Field)
+	assert.Equal(t, gorenogymodbus.CounterDecreased, violations[0].Kind)
+
+	assert.Empty(t, previous.ValidateAgainst(nil))
+}
+
+func TestValidatePartial(t *testing.T) {
+	dci, err := gorenogymodbus.ParseRange(0x100, []byte{0x02, 0x8f, 0xff, 0xff})
+	assert.NoError(t, err)
+
+	result := map[string]gorenogymodbus.ViolationKind{}
+	for _, v := range dci.Validate() {
+		result[v.Field] = v.Kind
+	}
+	assert.Equal(t, map[string]gorenogymodbus.ViolationKind{
+		"battery_capacity_soc": gorenogymodbus.OutOfRange,
+		"battery_voltage":      gorenogymodbus.OutOfRange,
+	}, result)
+}
@@ -0,0 +1,164 @@
+package gorenogymodbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DeviceIdentity identifies the controller a reading came from.
+type DeviceIdentity struct {
+	Model         string `json:"model"`
+	SerialNumber  string `json:"serial_number"`
+	DeviceAddress int    `json:"device_address"`
+}
+
+// Reading is a reading streamed by Watch. Time is when the request was sent and
+// Latency how long the controller took to answer it.
+type Reading struct {
+	Time    time.Time                     `json:"time"`
+	Latency time.Duration                 `json:"latency"`
+	Device  DeviceIdentity                `json:"device"`
+	Data    *DynamicControllerInformation `json:"data"`
+}
+
+// WatchPolicy is what Watch does with a reading while the consumer is behind.
+type WatchPolicy int
+
+const (
+	// WatchDropOldest replaces the oldest buffered reading, so the consumer always
+	// gets the most recent ones.
+	WatchDropOldest WatchPolicy = iota
+	// WatchBlock waits for the consumer, reading again only once the reading was
+	// received. Intervals passing meanwhile are skipped.
+	WatchBlock
+)
+
+func (p WatchPolicy) String() string {
+	switch p {
+	case WatchDropOldest:
+		return "drop-oldest"
+	case WatchBlock:
+		return "block"
+	default:
+		return "unknown"
+	}
+}
+
+type WatchConfig struct {
+	Interval time.Duration
+	Buffer   int // readings buffered for the consumer, at least one with WatchDropOldest
+	Policy   WatchPolicy
+}
+
+// Watch reads the controller now and every interval until ctx is done, dropping
+// the oldest reading when the consumer is behind. See WatchWithConfig.
+func (mc *ModbusClient) Watch(ctx context.Context, interval time.Duration) (<-chan Reading, <-chan error) {
+	return mc.WatchWithConfig(ctx, WatchConfig{Interval: interval})
+}
+
+// WatchWithConfig reads the controller on an interval until ctx is done and then
+// closes both channels. The product information is read first to identify the
+// device. Failed reads are sent on the error channel, they are dropped when nobody
+// is receiving them, and the next interval is tried again. The client must not be
+// used by others while watching.
+func (mc *ModbusClient) WatchWithConfig(ctx context.Context, config WatchConfig) (<-chan Reading, <-chan error) {
+	if config.Policy == WatchDropOldest && config.Buffer < 1 {
+		config.Buffer = 1
+	}
+
+	readings := make(chan Reading, config.Buffer)
+	errs := make(chan error, 1)
+
+	if config.Interval <= 0 {
+		errs <- fmt.Errorf("invalid watch interval: %s", config.Interval)
+		close(readings)
+		close(errs)
+		return readings, errs
+	}
+
+	go func() {
+		defer close(errs)
+		defer close(readings)
+
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+
+		var device *DeviceIdentity
+		for ctx.Err() == nil {
+			reading, err := mc.watchRead(&device)
+			if err != nil {
+				select {
+				case errs <- err:
+				default:
+				}
+			} else if !sendReading(ctx, readings, reading, config.Policy) {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return readings, errs
+}
+
+func (mc *ModbusClient) watchRead(device **DeviceIdentity) (Reading, error) {
+	if *device == nil {
+		pi, err := mc.ReadProductInformation()
+		if err != nil {
+			return Reading{}, fmt.Errorf("failed to identify device: %w", err)
+		}
+		*device = &DeviceIdentity{
+			Model:         pi.Model,
+			SerialNumber:  pi.SerialNumber,
+			DeviceAddress: pi.DeviceAddress,
+		}
+	}
+
+	start := time.Now()
+	res, err := mc.ReadData()
+	latency := time.Since(start)
+	if err != nil {
+		return Reading{}, err
+	}
+
+	dci, err := Parse(res)
+	if err != nil {
+		return Reading{}, fmt.Errorf("failed to parse reading: %w", err)
+	}
+
+	return Reading{Time: start, Latency: latency, Device: **device, Data: dci}, nil
+}
+
+// sendReading sends a reading according to policy, returning false when ctx is done.
+func sendReading(ctx context.Context, readings chan Reading, r Reading, policy WatchPolicy) bool {
+	if policy == WatchBlock {
+		select {
+		case readings <- r:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case readings <- r:
+			return true
+		default:
+		}
+
+		// full, make room unless the consumer just did
+		select {
+		case <-readings:
+		default:
+		}
+	}
+}
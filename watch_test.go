@@ -0,0 +1,93 @@
+package gorenogymodbus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/michaelpeterswa/go-renogy-modbus/simulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func simulated(t *testing.T) (*gorenogymodbus.ModbusClient, *simulator.Server) {
+	now := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	s := simulator.NewServer(1, simulator.NewModel(simulator.ModelConfig{Clock: func() time.Time { return now }}))
+	s.Injector = simulator.NewInjector(1)
+	return &gorenogymodbus.ModbusClient{Client: s.Client()}, s
+}
+
+func TestWatch(t *testing.T) {
+	mc, s := simulated(t)
+	s.Injector.Add(simulator.Rule{
+		Injection: simulator.ExceptionResponse,
+		Exception: simulator.ExceptionServerDeviceFailure,
+		Match:     func(function byte, address uint16) bool { return address == 0x100 },
+		After:     1,
+		Count:     1,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	readings, errs := mc.Watch(ctx, 5*time.Millisecond)
+
+	var got []gorenogymodbus.Reading
+	for r := range readings {
+		got = append(got, r)
+		if len(got) == 3 {
+			cancel()
+		}
+	}
+
+	require.GreaterOrEqual(t, len(got), 3)
+	for _, r := range got {
+		assert.Equal(t, gorenogymodbus.DeviceIdentity{Model: "RNG-CTRL-RVR40", SerialNumber: "20230810", DeviceAddress: 1}, r.Device)
+		assert.Equal(t, 60, r.Data.BatteryCapacitySOC)
+		assert.Greater(t, r.Latency, time.Duration(0))
+	}
+	assert.GreaterOrEqual(t, got[1].Time.Sub(got[0].Time), 5*time.Millisecond)
+
+	err, ok := <-errs
+	require.True(t, ok)
+	assert.ErrorContains(t, err, "exception '4'")
+	_, ok = <-errs
+	assert.False(t, ok, "closed after cancel")
+}
+
+func TestWatchPolicy(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Policy gorenogymodbus.WatchPolicy
+		Fresh  bool
+	}{
+		{Name: "drop oldest", Policy: gorenogymodbus.WatchDropOldest, Fresh: true},
+		{Name: "block", Policy: gorenogymodbus.WatchBlock, Fresh: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			mc, _ := simulated(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			start := time.Now()
+			readings, _ := mc.WatchWithConfig(ctx, gorenogymodbus.WatchConfig{Interval: 2 * time.Millisecond, Policy: tc.Policy})
+
+			// fall behind
+			time.Sleep(50 * time.Millisecond)
+			r := <-readings
+			assert.Equal(t, tc.Fresh, r.Time.Sub(start) > 25*time.Millisecond, "reading from %s after start", r.Time.Sub(start))
+
+			cancel()
+			for range readings {
+			}
+		})
+	}
+
+	mc, _ := simulated(t)
+	readings, errs := mc.Watch(context.Background(), 0)
+	assert.EqualError(t, <-errs, "invalid watch interval: 0s")
+	_, ok := <-readings
+	assert.False(t, ok)
+}
@@ -0,0 +1,119 @@
+package gorenogymodbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// settingsRegisters are the writable settings registers in the order of the
+// settings block synthesized by Settings.Synthesize, with the fields they hold.
+var settingsRegisters = []struct {
+	Address uint16
+	Fields  []string
+}{
+	{0xE002, []string{"nominal_battery_capacity"}},
+	{0xE003, []string{"system_voltage"}}, // recognized voltage is read only
+	{0xE004, []string{"battery_type"}},
+	{0xE005, []string{"over_voltage_threshold"}},
+	{0xE006, []string{"charging_limit_voltage"}},
+	{0xE007, []string{"equalizing_charging_voltage"}},
+	{0xE008, []string{"boost_charging_voltage"}},
+	{0xE009, []string{"floating_charging_voltage"}},
+	{0xE00A, []string{"boost_charging_recovery_voltage"}},
+	{0xE00B, []string{"over_discharge_recovery_voltage"}},
+	{0xE00C, []string{"under_voltage_warning_level"}},
+	{0xE00D, []string{"over_discharge_voltage"}},
+	{0xE00E, []string{"discharging_limit_voltage"}},
+	{0xE00F, []string{"end_of_charge_soc", "end_of_discharge_soc"}},
+	{0xE010, []string{"over_discharge_time_delay"}},
+	{0xE011, []string{"equalizing_charging_time"}},
+	{0xE012, []string{"boost_charging_time"}},
+	{0xE013, []string{"equalizing_charging_interval"}},
+	{0xE014, []string{"temperature_compensation_factor"}},
+	{0xE01D, []string{"load_working_mode"}},
+}
+
+// SettingsWrite is a write of consecutive settings registers.
+type SettingsWrite struct {
+	Address uint16   `json:"address"`
+	Values  []uint16 `json:"values"`
+	Fields  []string `json:"fields"` // json names of the settings written
+}
+
+func (sw SettingsWrite) String() string {
+	values := make([]string, len(sw.Values))
+	for i, v := range sw.Values {
+		values[i] = fmt.Sprintf("0x%04X", v)
+	}
+
+	registers := fmt.Sprintf("0x%04X", sw.Address)
+	if len(sw.Values) > 1 {
+		registers += fmt.Sprintf("-0x%04X", sw.Address+uint16(len(sw.Values))-1)
+	}
+	return fmt.Sprintf("%s = %s (%s)", registers, strings.Join(values, " "), strings.Join(sw.Fields, ", "))
+}
+
+// PlanSettingsWrites returns the writes that change the current settings into the
+// desired ones, touching only registers that differ and coalescing consecutive
+// registers into one write.
+func PlanSettingsWrites(current, desired *Settings) ([]SettingsWrite, error) {
+	from, err := current.Synthesize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to synthesize current settings: %w", err)
+	}
+
+	// recognized voltage is read only, take it from the current settings
+	d := *desired
+	d.RecognizedVoltage = current.RecognizedVoltage
+	to, err := d.Synthesize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to synthesize desired settings: %w", err)
+	}
+
+	var writes []SettingsWrite
+	for i, register := range settingsRegisters {
+		value := binary.BigEndian.Uint16(to[i*2:])
+		if value == binary.BigEndian.Uint16(from[i*2:]) {
+			continue
+		}
+
+		if n := len(writes); n > 0 {
+			last := &writes[n-1]
+			if last.Address+uint16(len(last.Values)) == register.Address {
+				last.Values = append(last.Values, value)
+				last.Fields = append(last.Fields, register.Fields...)
+				continue
+			}
+		}
+		writes = append(writes, SettingsWrite{
+			Address: register.Address,
+			Values:  []uint16{value},
+			Fields:  append([]string(nil), register.Fields...),
+		})
+	}
+
+	return writes, nil
+}
+
+// WriteSettings applies settings writes in order, single registers with write
+// single register (0x06) and longer runs with write multiple registers (0x10).
+func (mc *ModbusClient) WriteSettings(writes []SettingsWrite) error {
+	for _, w := range writes {
+		var err error
+		if len(w.Values) == 1 {
+			_, err = mc.Client.WriteSingleRegister(w.Address, w.Values[0])
+		} else {
+			values := make([]byte, 0, len(w.Values)*2)
+			for _, v := range w.Values {
+				values = binary.BigEndian.AppendUint16(values, v)
+			}
+			_, err = mc.Client.WriteMultipleRegisters(w.Address, uint16(len(w.Values)), values)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write settings registers %s: %w", w, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package gorenogymodbus_test
+
+import (
+	"testing"
+
+	"github.com/goburrow/modbus"
+	gorenogymodbus "github.com/michaelpeterswa/go-renogy-modbus"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanSettingsWrites(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(s *gorenogymodbus.Settings)
+		expected []gorenogymodbus.SettingsWrite
+	}{
+		{
+			name:   "unchanged",
+			modify: func(s *gorenogymodbus.Settings) { s.RecognizedVoltage = 24 },
+		},
+		{
+			name:   "single register",
+			modify: func(s *gorenogymodbus.Settings) { s.LoadWorkingMode = 0 },
+			expected: []gorenogymodbus.SettingsWrite{
+				{Address: 0xE01D, Values: []uint16{0x0000}, Fields: []string{"load_working_mode"}},
+			},
+		},
+		{
+			name: "coalesced",
+			modify: func(s *gorenogymodbus.Settings) {
+				s.BoostChargingVoltage = decimal.NewFromFloat(14.2)
+				s.FloatingChargingVoltage = decimal.NewFromFloat(13.6)
+				s.EndOfDischargeSOC = 20
+				s.BatteryType = gorenogymodbus.UserDefinedBattery.String()
+			},
+			expected: []gorenogymodbus.SettingsWrite{
+				{Address: 0xE004, Values: []uint16{0x0000}, Fields: []string{"battery_type"}},
+				{Address: 0xE008, Values: []uint16{0x008E, 0x0088}, Fields: []string{"boost_charging_voltage", "floating_charging_voltage"}},
+				{Address: 0xE00F, Values: []uint16{0x6414}, Fields: []string{"end_of_charge_soc", "end_of_discharge_soc"}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			current := sealedSettings()
+			desired := sealedSettings()
+			tc.modify(&desired)
+
+			writes, err := gorenogymodbus.PlanSettingsWrites(&current, &desired)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, writes)
+		})
+	}
+}
+
+func TestSettingsWriteString(t *testing.T) {
+	w := gorenogymodbus.SettingsWrite{Address: 0xE008, Values: []uint16{0x008E, 0x0088}, Fields: []string{"boost_charging_voltage", "floating_charging_voltage"}}
+	assert.Equal(t, "0xE008-0xE009 = 0x008E 0x0088 (boost_charging_voltage, floating_charging_voltage)", w.String())
+}
+
+type fakeWriteClient struct {
+	modbus.Client
+	single   map[uint16]uint16
+	multiple map[uint16][]byte
+}
+
+func (c *fakeWriteClient) WriteSingleRegister(address, value uint16) ([]byte, error) {
+	c.single[address] = value
+	return nil, nil
+}
+
+func (c *fakeWriteClient) WriteMultipleRegisters(address, quantity uint16, value []byte) ([]byte, error) {
+	c.multiple[address] = value
+	return nil, nil
+}
+
+func TestWriteSettings(t *testing.T) {
+	c := &fakeWriteClient{single: map[uint16]uint16{}, multiple: map[uint16][]byte{}}
+	mc := &gorenogymodbus.ModbusClient{Client: c}
+
+	require.NoError(t, mc.WriteSettings([]gorenogymodbus.SettingsWrite{
+		{Address: 0xE004, Values: []uint16{0x0000}},
+		{Address: 0xE008, Values: []uint16{0x008E, 0x0088}},
+	}))
+	assert.Equal(t, map[uint16]uint16{0xE004: 0x0000}, c.single)
+	assert.Equal(t, map[uint16][]byte{0xE008: {0x00, 0x8E, 0x00, 0x88}}, c.multiple)
+}